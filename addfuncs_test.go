@@ -0,0 +1,171 @@
+package templatemanager
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var addFuncsTemplates = map[string]string{
+	"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`,
+	"main/home.tpl.html":             `{{ define "content" }}{{ upper .name }}{{ end }}`,
+}
+
+func executeToString(tm *TemplateManager, name string) (string, error) {
+	out := &bytes.Buffer{}
+	err := tm.ExecuteTemplate(out, name, map[string]string{"name": "alice"})
+	return out.String(), err
+}
+
+func TestTemplateManager_AddFuncs(t *testing.T) {
+	tm := newTestManager(t, false, addFuncsTemplates, func(conf *TemplateConfig) { conf.FuncMap["upper"] = strings.ToUpper })
+	// "main/plugin.tpl.html" could not be parsed before "md_bold" is added.
+	writeTemplateFiles(t, tm.Config.DirOfRoot, map[string]string{
+		"main/plugin.tpl.html": `{{ define "content" }}{{ md_bold .name }}{{ end }}`,
+	})
+	if got, err := executeToString(tm, "main/home.tpl.html"); err != nil || got != "<html>ALICE</html>" {
+		t.Fatalf("ExecuteTemplate() = %q, %v", got, err)
+	}
+
+	tests := []struct {
+		name    string
+		funcs   template.FuncMap
+		replace bool
+		wantErr string
+	}{
+		{"include", template.FuncMap{"include": strings.ToUpper}, false, `"include" is a builtin function`},
+		{"builtin", template.FuncMap{"printf": strings.ToUpper}, false, `"printf" is a builtin function`},
+		{"existing", template.FuncMap{"upper": strings.ToLower}, false, `"upper" already exists`},
+		{"not exist", template.FuncMap{"lower": strings.ToLower}, true, `"lower" does not exist`},
+		{"invalid name", template.FuncMap{"a-b": strings.ToLower}, false, `"a-b" is not a valid function name`},
+		{"not a func", template.FuncMap{"answer": 42}, false, `"answer" is not a function`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			if tt.replace {
+				err = tm.ReplaceFuncs(tt.funcs)
+			} else {
+				err = tm.AddFuncs(tt.funcs)
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// nothing is changed by failed updates.
+	if _, ok := tm.Config.FuncMap["lower"]; ok {
+		t.Errorf("FuncMap should not be changed")
+	}
+
+	bold := func(s string) template.HTML { return template.HTML("<b>" + s + "</b>") }
+	if err := tm.AddFuncsWithNamespace("md", template.FuncMap{"bold": bold}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.AddFuncsWithNamespace("md", template.FuncMap{"bold": bold}); err == nil {
+		t.Errorf("AddFuncsWithNamespace() should not overwrite %q", "md_bold")
+	}
+	if err := tm.AddFuncsWithNamespace("m-d", template.FuncMap{"bold": bold}); err == nil {
+		t.Errorf("AddFuncsWithNamespace() should fail with an invalid namespace")
+	}
+	if got, err := executeToString(tm, "main/plugin.tpl.html"); err != nil || got != "<html><b>alice</b></html>" {
+		t.Errorf("ExecuteTemplate() = %q, %v", got, err)
+	}
+
+	// cached templates are re-parsed with the replaced function.
+	if err := tm.ReplaceFuncs(template.FuncMap{"upper": func(s string) string { return "[" + s + "]" }}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := executeToString(tm, "main/home.tpl.html"); err != nil || got != "<html>[alice]</html>" {
+		t.Errorf("ExecuteTemplate() = %q, %v", got, err)
+	}
+}
+
+func TestTemplateManager_AddFuncsAtomic(t *testing.T) {
+	tm := newTestManager(t, false, addFuncsTemplates, func(conf *TemplateConfig) { conf.FuncMap["upper"] = strings.ToUpper })
+	if _, err := executeToString(tm, "main/home.tpl.html"); err != nil {
+		t.Fatal(err)
+	}
+	old, _ := tm.GetTemplate("C->main/home.tpl.html")
+
+	// a broken template file fails the re-parse, the cache and FuncMap are kept.
+	writeTemplateFiles(t, tm.Config.DirOfRoot, map[string]string{
+		"main/home.tpl.html": `{{ define "content" }}{{ upper .name }`,
+	})
+	if err := tm.AddFuncs(template.FuncMap{"lower": strings.ToLower}); err == nil {
+		t.Fatalf("AddFuncs() should fail")
+	}
+	if tpl, _ := tm.GetTemplate("C->main/home.tpl.html"); tpl != old {
+		t.Errorf("cached template should not be changed")
+	}
+	if _, ok := tm.Config.FuncMap["lower"]; ok {
+		t.Errorf("FuncMap should not be changed")
+	}
+	// versions are not recorded, so the broken file is still reported as changed.
+	if changed, _ := tm.ReloadChanged(); !ContainsString(changed, "C->main/home.tpl.html") {
+		t.Errorf("ReloadChanged() got = %q, want the broken template", changed)
+	}
+}
+
+func TestTemplateManager_AddFuncsConcurrently(t *testing.T) {
+	tm := newTestManager(t, false, addFuncsTemplates, func(conf *TemplateConfig) { conf.FuncMap["upper"] = strings.ToUpper })
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := executeToString(tm, "main/home.tpl.html"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		if err := tm.AddFuncs(template.FuncMap{fmt.Sprintf("f%d", i): strings.ToLower}); err != nil {
+			t.Error(err)
+		}
+	}
+	wg.Wait()
+}
+
+func TestTemplateManager_ReplaceFuncsWhileParsingLazily(t *testing.T) {
+	files := map[string]string{"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`}
+	var names []string
+	for i := 0; i < 20; i++ {
+		names = append(names, fmt.Sprintf("main/page%d.tpl.html", i))
+		files[names[i]] = `{{ define "content" }}{{ upper .name }}{{ end }}`
+	}
+	conf := newTestConfig(t, false, files)
+	conf.FuncMap["upper"] = strings.ToUpper
+	tm := New(conf)
+	if err := tm.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	// templates parsed lazily during ReplaceFuncs are not cached with the old FuncMap.
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			if _, err := executeToString(tm, name); err != nil {
+				t.Error(err)
+			}
+		}(name)
+	}
+	if err := tm.ReplaceFuncs(template.FuncMap{"upper": strings.ToLower}); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+	for _, name := range names {
+		if got, err := executeToString(tm, name); err != nil || got != "<html>alice</html>" {
+			t.Errorf("ExecuteTemplate(%q) = %q, %v, want the replaced func", name, got, err)
+		}
+	}
+}
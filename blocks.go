@@ -0,0 +1,157 @@
+package templatemanager
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// Required blocks.
+//
+// The layout calls blocks(eg: {{ template "title" . }} and {{ template "content" . }}) which main templates should define.
+// At Init, the parse trees of every main template(ContextMode) are checked:
+//   - Missing: templates called(by the layout, or by templates it calls) but defined nowhere. eg: a page forgets "content"
+//   - Unused: templates defined in the main file but never called from the layout.
+//
+// Missing blocks fail Init if StrictBlocks is true, otherwise they are logged. Unused blocks are always logged.
+
+// BlockReport is the result of checking the blocks of a main template.
+type BlockReport struct {
+	TemplateName string   // standard template name. eg: "C->main/demo/demo1.tpl.html"
+	Missing      []string // templates called but not defined
+	Unused       []string // templates defined in the main file but never called
+}
+
+// CheckBlocks checks the blocks of every main template, only templates with problems are returned.
+func (tm *TemplateManager) CheckBlocks() []BlockReport {
+	var reports []BlockReport
+	for _, f := range tm.getMainFiles() {
+		te := NewTemplateEnvByParsing(tm.getBasicTemplateNameByFilePath(f))
+		te.ToContextMode()
+		tpl, ok := tm.GetTemplate(te.StandardTemplateName())
+		if !ok {
+			// not parsed(eg: a broken sandboxed template)
+			continue
+		}
+		report := tm.checkBlocksOfTemplate(tpl, te)
+		if len(report.Missing) > 0 || len(report.Unused) > 0 {
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
+func (tm *TemplateManager) checkBlocksOfTemplate(tpl *template.Template, te *TemplateEnv) BlockReport {
+	report := BlockReport{TemplateName: te.StandardTemplateName()}
+	var called map[string]bool
+	called, report.Missing = getReachableTemplateNames(tpl, tm.getEntryName(te))
+	for _, name := range te.Names {
+		for _, definedName := range tm.getDefinedTemplateNames(path.Join(tm.Config.DirOfRoot, name)) {
+			if !called[definedName] && !ContainsString(report.Unused, definedName) {
+				report.Unused = append(report.Unused, definedName)
+			}
+		}
+	}
+	sort.Strings(report.Missing)
+	sort.Strings(report.Unused)
+	return report
+}
+
+// getReachableTemplateNames returns the templates called from the entry(recursively), and the called ones which are not defined.
+func getReachableTemplateNames(tpl *template.Template, entry string) (called map[string]bool, missing []string) {
+	called = make(map[string]bool)
+	queue := []string{entry}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if called[name] {
+			continue
+		}
+		called[name] = true
+		t := tpl.Lookup(name)
+		if t == nil || t.Tree == nil {
+			missing = append(missing, name)
+			continue
+		}
+		queue = append(queue, getCalledTemplateNames(t.Tree.Root)...)
+	}
+	return called, missing
+}
+
+// getDefinedTemplateNames returns the templates defined({{ define }} or {{ block }}) in the file.
+func (tm *TemplateManager) getDefinedTemplateNames(filePath string) []string {
+	b, err := tm.readTemplateFile(filePath)
+	if err != nil {
+		return nil
+	}
+	funcs := make(map[string]interface{})
+	for _, name := range sandboxBuiltinFuncs {
+		funcs[name] = true
+	}
+	for name := range tm.getFuncMap() {
+		funcs[name] = true
+	}
+	left, right := tm.Config.Delims.get()
+	trees, err := parse.Parse(path.Base(filePath), string(b), left, right, funcs)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for name := range trees {
+		// the template of the file itself
+		if name != path.Base(filePath) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getCalledTemplateNames returns the names of {{ template "name" }}(and {{ block "name" }}) under the node.
+func getCalledTemplateNames(node parse.Node) []string {
+	var names []string
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			names = append(names, getCalledTemplateNames(child)...)
+		}
+	case *parse.TemplateNode:
+		names = append(names, n.Name)
+	case *parse.IfNode:
+		names = append(getCalledTemplateNames(n.List), getCalledTemplateNames(n.ElseList)...)
+	case *parse.RangeNode:
+		names = append(getCalledTemplateNames(n.List), getCalledTemplateNames(n.ElseList)...)
+	case *parse.WithNode:
+		names = append(getCalledTemplateNames(n.List), getCalledTemplateNames(n.ElseList)...)
+	}
+	return names
+}
+
+// checkBlocks logs the block reports, it returns an error of missing blocks if StrictBlocks is true.
+func (tm *TemplateManager) checkBlocks() error {
+	var errs []string
+	for _, report := range tm.CheckBlocks() {
+		if len(report.Missing) > 0 {
+			msg := fmt.Sprintf("template %q misses blocks: %q", report.TemplateName, report.Missing)
+			if tm.Config.StrictBlocks {
+				errs = append(errs, msg)
+			} else {
+				log.Printf("Warning: %s", msg)
+			}
+		}
+		if len(report.Unused) > 0 {
+			log.Printf("Warning: template %q defines blocks which are never called: %q", report.TemplateName, report.Unused)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("missing blocks of %d templates: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return nil
+}
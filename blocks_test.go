@@ -0,0 +1,38 @@
+package templatemanager
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplateManager_CheckBlocks(t *testing.T) {
+	conf := newTestConfig(t, false, map[string]string{
+		"context/layout/layout.tpl.html": `<title>{{ template "title" . }}</title>{{ if . }}{{ template "content" . }}{{ end }}{{ block "footer" . }}footer{{ end }}`,
+		"main/ok.tpl.html":               `{{ define "title" }}ok{{ end }}{{ define "content" }}ok{{ end }}`,
+		"main/footer.tpl.html":           `{{ define "title" }}footer{{ end }}{{ define "content" }}ok{{ end }}{{ define "footer" }}custom footer{{ end }}`,
+		"main/missing.tpl.html":          `{{ define "title" }}missing{{ end }}`,
+		"main/nested.tpl.html":           `{{ define "title" }}nested{{ end }}{{ define "content" }}{{ range . }}{{ template "item" . }}{{ end }}{{ end }}`,
+		"main/unused.tpl.html":           `{{ define "title" }}unused{{ end }}{{ define "content" }}unused{{ end }}{{ define "sidebar" }}sidebar{{ end }}`,
+	})
+	tm := New(conf)
+	if err := tm.Init(true); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	want := []BlockReport{
+		{TemplateName: "C->main/missing.tpl.html", Missing: []string{"content"}},
+		{TemplateName: "C->main/nested.tpl.html", Missing: []string{"item"}},
+		{TemplateName: "C->main/unused.tpl.html", Unused: []string{"sidebar"}},
+	}
+	if got := tm.CheckBlocks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckBlocks() = %+v, want %+v", got, want)
+	}
+
+	conf.StrictBlocks = true
+	err := New(conf).Init(true)
+	for _, s := range []string{"missing blocks of 2 templates", `"C->main/missing.tpl.html" misses blocks: ["content"]`, `"C->main/nested.tpl.html" misses blocks: ["item"]`} {
+		if err == nil || !strings.Contains(err.Error(), s) {
+			t.Errorf("Init() error = %v, want %q", err, s)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package templatemanager
+
+import (
+	"path"
+	"strings"
+)
+
+// Cascading context dirs.
+//
+// A dir named CascadingContextDirName(default: "_context") inside the main dir contributes context templates
+// to all main templates beneath its parent dir. They are parsed after the global context files,
+// outermost first, so the nearest definition wins. eg: "main/admin/users/list.tpl.html" is parsed with
+//
+//	context/**                      (DirOfContextRelativeToRoot)
+//	main/_context/**
+//	main/admin/_context/**
+//	main/admin/users/_context/**
+//	main/admin/users/list.tpl.html
+//
+// A file in a cascading context dir named as the base name of FilePathOfLayoutRelativeToRoot(eg: "layout.tpl.html")
+// is the layout of the templates beneath, unless the layout option is set.
+
+// isInCascadingContextDir checks if the file(basic name) is in a cascading context dir.
+func (tm *TemplateManager) isInCascadingContextDir(basicName string) bool {
+	name := tm.Config.CascadingContextDirName
+	if name == "" || !isInDir(basicName, tm.Config.DirOfMainRelativeToRoot) {
+		return false
+	}
+	return ContainsString(strings.Split(path.Dir(basicName), "/"), name)
+}
+
+// getCascadingContextDirs returns the cascading context dirs(relative to root) of the main template(basic name), outermost first.
+func (tm *TemplateManager) getCascadingContextDirs(basicName string) []string {
+	name := tm.Config.CascadingContextDirName
+	if name == "" || !isInDir(basicName, tm.Config.DirOfMainRelativeToRoot) {
+		return nil
+	}
+	mainDir := strings.Trim(path.Clean("/"+tm.Config.DirOfMainRelativeToRoot), "/")
+	dir := mainDir
+	dirs := []string{path.Join(dir, name)}
+	for _, part := range strings.Split(strings.TrimPrefix(path.Dir(basicName), mainDir), "/") {
+		if part == "" {
+			continue
+		}
+		dir = path.Join(dir, part)
+		dirs = append(dirs, path.Join(dir, name))
+	}
+	return dirs
+}
+
+// getAllCascadingContextFiles returns the file paths of templates in all cascading context dirs.
+func (tm *TemplateManager) getAllCascadingContextFiles() []string {
+	if tm.Config.CascadingContextDirName == "" {
+		return nil
+	}
+	filePaths, err := tm.getTemplateFilePathsByLoader(tm.Config.DirOfMainRelativeToRoot)
+	if err != nil {
+		return nil
+	}
+	for _, f := range tm.getStringTemplateFilePaths(tm.getDirOfMain()) {
+		if !ContainsString(filePaths, f) {
+			filePaths = append(filePaths, f)
+		}
+	}
+	var cascadingFiles []string
+	for _, f := range filePaths {
+		if tm.isInCascadingContextDir(tm.getBasicTemplateNameByFilePath(f)) {
+			cascadingFiles = append(cascadingFiles, f)
+		}
+	}
+	return cascadingFiles
+}
+
+// buildCascadingIndex indexes the file paths of templates in all cascading context dirs by the dir(relative to root).
+func (tm *TemplateManager) buildCascadingIndex() map[string][]string {
+	index := make(map[string][]string)
+	for _, f := range tm.getAllCascadingContextFiles() {
+		tm.addToCascadingIndex(index, f)
+	}
+	return index
+}
+
+// addToCascadingIndex adds the file path to every cascading context dir it is in.
+func (tm *TemplateManager) addToCascadingIndex(index map[string][]string, filePath string) {
+	parts := strings.Split(path.Dir(tm.getBasicTemplateNameByFilePath(filePath)), "/")
+	for i, part := range parts {
+		dir := path.Join(parts[:i+1]...)
+		if part == tm.Config.CascadingContextDirName && !ContainsString(index[dir], filePath) {
+			index[dir] = append(index[dir], filePath)
+		}
+	}
+}
+
+// getCascadingContextFiles returns the file paths of templates in the cascading context dirs of
+// the main template(basic name), outermost first. The index is built by Prepare(or ReloadChanged).
+func (tm *TemplateManager) getCascadingContextFiles(basicName string) []string {
+	dirs := tm.getCascadingContextDirs(basicName)
+	if len(dirs) == 0 {
+		return nil
+	}
+	tm.resolveMutex.Lock()
+	defer tm.resolveMutex.Unlock()
+	if tm.cascadingIndex == nil {
+		tm.cascadingIndex = tm.buildCascadingIndex()
+	}
+	var filePaths []string
+	for _, dir := range dirs {
+		filePaths = append(filePaths, tm.cascadingIndex[dir]...)
+	}
+	return filePaths
+}
+
+// getCascadingLayout returns the nearest layout file path of cascading context dirs of the main template(basic name), "" if none.
+func (tm *TemplateManager) getCascadingLayout(basicName string) string {
+	layoutName := path.Base(tm.Config.FilePathOfLayoutRelativeToRoot)
+	layout := ""
+	for _, f := range tm.getCascadingContextFiles(basicName) {
+		if path.Base(f) == layoutName {
+			layout = f
+		}
+	}
+	return layout
+}
+
+// getAllContextFiles returns the global context files and the files of all cascading context dirs.
+func (tm *TemplateManager) getAllContextFiles() []string {
+	return append(tm.getContextFiles(), tm.getAllCascadingContextFiles()...)
+}
@@ -0,0 +1,93 @@
+package templatemanager
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTemplateManager_CascadingContext(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "templates")
+	writeTemplateFiles(t, root, map[string]string{
+		"context/layout/layout.tpl.html":          `<html>{{ template "nav" . }}|{{ template "content" . }}</html>`,
+		"context/partial/nav.tpl.html":            `{{ define "nav" }}global nav{{ end }}`,
+		"main/home.tpl.html":                      `{{ define "content" }}home{{ end }}`,
+		"main/admin/_context/nav.tpl.html":        `{{ define "nav" }}admin nav{{ end }}`,
+		"main/admin/dashboard.tpl.html":           `{{ define "content" }}dashboard{{ end }}`,
+		"main/admin/users/_context/nav.tpl.html":  `{{ define "nav" }}users nav{{ end }}`,
+		"main/admin/users/list.tpl.html":          `{{ define "content" }}list{{ end }}`,
+		"main/shop/_context/layout.tpl.html":      `<shop>{{ template "content" . }}</shop>`,
+		"main/shop/_context/partial/x.tpl.html":   `{{ define "x" }}x{{ end }}`,
+		"main/shop/cart.tpl.html":                 `{{ define "content" }}cart {{ template "x" . }}{{ end }}`,
+		"main/shop/_drafts/wishlist.tpl.html":     `{{ define "content" }}wishlist{{ end }}`,
+		"main/shop/_context/_drafts/old.tpl.html": `{{ define "x" }}old{{ end }}`,
+	})
+
+	conf := NewDefaultConfig(false)
+	conf.DirOfRoot = root
+	conf.SkipDrafts = true
+	tm := New(conf)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+
+	var mainFiles []string
+	for _, f := range tm.getMainFiles() {
+		mainFiles = append(mainFiles, tm.getBasicTemplateNameByFilePath(f))
+	}
+	wantMainFiles := []string{"main/admin/dashboard.tpl.html", "main/admin/users/list.tpl.html", "main/home.tpl.html", "main/shop/cart.tpl.html"}
+	if !reflect.DeepEqual(mainFiles, wantMainFiles) {
+		t.Errorf("getMainFiles() = %q, want %q", mainFiles, wantMainFiles)
+	}
+
+	tests := []struct {
+		templateName string
+		want         string
+	}{
+		{"home", "<html>global nav|home</html>"},
+		{"admin/dashboard", "<html>admin nav|dashboard</html>"},
+		{"admin/users/list", "<html>users nav|list</html>"},
+		{"shop/cart", "<shop>cart x</shop>"},
+		{"shop/cart?layout=context/layout/layout.tpl.html", "<html>global nav|cart x</html>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.templateName, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := tm.ExecuteTemplate(out, tt.templateName, nil); err != nil || out.String() != tt.want {
+				t.Errorf("ExecuteTemplate() got = %q, err = %v, want %q", out.String(), err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateManager_CascadingContextIndex(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "templates")
+	writeTemplateFiles(t, root, map[string]string{
+		"context/layout/layout.tpl.html":   `<html>{{ template "nav" . }}|{{ template "content" . }}</html>`,
+		"context/partial/nav.tpl.html":     `{{ define "nav" }}global nav{{ end }}`,
+		"main/admin/_context/nav.tpl.html": `{{ define "nav" }}admin nav{{ end }}`,
+		"main/admin/users/list.tpl.html":   `{{ define "content" }}list{{ end }}`,
+	})
+	conf := NewDefaultConfig(true)
+	conf.DirOfRoot = root
+	conf.VerboseLevel = 0
+	tm := New(conf)
+	if err := tm.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{filepath.Join(root, "main/admin/_context/nav.tpl.html")}
+	if got := tm.getCascadingContextFiles("main/admin/users/list.tpl.html"); !reflect.DeepEqual(got, want) {
+		t.Errorf("getCascadingContextFiles() = %q, want %q", got, want)
+	}
+
+	// a string template in a cascading context dir is added to the index.
+	if err := tm.AddTemplateString("main/admin/users/_context/nav.tpl.html", `{{ define "nav" }}users nav{{ end }}`); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := tm.ExecuteTemplate(out, "admin/users/list", nil); err != nil || out.String() != "<html>users nav|list</html>" {
+		t.Errorf("ExecuteTemplate() got = %q, err = %v", out.String(), err)
+	}
+}
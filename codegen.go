@@ -0,0 +1,262 @@
+package templatemanager
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// Code generation, see: "templatemanager generate" of main/.
+//
+// GenerateCode emits Go constants of the standard names of main templates(ContextMode):
+//
+//	const TemplateDemoDemo1 = "C->main/demo/demo1.tpl.html"
+//
+// and typed render functions(GenerateOptions.RenderFuncs) of main templates which declare data fields in comments:
+//
+//	{{/*
+//	@data tplName string
+//	@data now     time.Time
+//	@import time
+//	*/}}
+//
+//	type DemoDemo1Data struct { TplName string; Now time.Time }
+//	func RenderDemoDemo1(tm *templatemanager.TemplateManager, w io.Writer, data DemoDemo1Data) error
+//
+// Fields accessed by the template(including the layout) must be declared, see: "Data fields".
+// "@import" is optional for standard packages(eg: time), the qualifier is used as the import path.
+
+const (
+	CodegenDataTag   = "@data"
+	CodegenImportTag = "@import"
+)
+
+type GenerateOptions struct {
+	Package     string // package name of the generated code, default: "templates"
+	RenderFuncs bool   // true: generate typed render functions of templates with "@data" comments
+}
+
+type codegenField struct {
+	Key  string // key of the data map. eg: "tplName"
+	Name string // name of the struct field. eg: "TplName"
+	Type string // eg: "time.Time"
+}
+
+type codegenTemplate struct {
+	Name         string // standard template name
+	BasicName    string // path relative to root
+	Ident        string // eg: "DemoDemo1"
+	Fields       []codegenField
+	HasDataTypes bool
+}
+
+var (
+	codegenQualifierRegexp = regexp.MustCompile(`\b([a-z_][a-zA-Z0-9_]*)\.[A-Z]`)
+	codegenIdentRegexp     = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+var codegenSourceTemplate = template.Must(template.New("codegen").Parse(`// Code generated by templatemanager generate; DO NOT EDIT.
+
+package {{ .Package }}
+{{ if or .StdImports .Imports }}
+import (
+{{- range .StdImports }}
+	"{{ . }}"
+{{- end }}
+{{ range .Imports }}
+	"{{ . }}"
+{{- end }}
+)
+{{ end }}
+// Standard template names of main templates.
+const (
+{{- range .Templates }}
+	Template{{ .Ident }} = {{ printf "%q" .Name }}
+{{- end }}
+)
+{{ range .Templates }}{{ if .HasDataTypes }}
+// {{ .Ident }}Data is the data of {{ printf "%q" .BasicName }}.
+type {{ .Ident }}Data struct {
+{{- range .Fields }}
+	{{ .Name }} {{ .Type }}
+{{- end }}
+}
+
+// Render{{ .Ident }} renders {{ printf "%q" .Name }} with data.
+func Render{{ .Ident }}(tm *templatemanager.TemplateManager, w io.Writer, data {{ .Ident }}Data) error {
+	return tm.ExecuteTemplate(w, Template{{ .Ident }}, map[string]interface{}{
+{{- range .Fields }}
+		{{ printf "%q" .Key }}: data.{{ .Name }},
+{{- end }}
+	})
+}
+{{ end }}{{ end }}`))
+
+// getCodegenIdent returns the Go identifier of the template. eg: "main/demo/demo1.tpl.html" -> "DemoDemo1"
+func (tm *TemplateManager) getCodegenIdent(basicName string) string {
+	mainDir := strings.Trim(path.Clean("/"+tm.Config.DirOfMainRelativeToRoot), "/")
+	name := strings.TrimPrefix(trimAllExt(basicName), mainDir+"/")
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	ident := ""
+	for _, word := range words {
+		ident += strings.ToUpper(word[:1]) + word[1:]
+	}
+	if ident == "" || unicode.IsDigit(rune(ident[0])) {
+		ident = "Page" + ident
+	}
+	return ident
+}
+
+// parseCodegenComments returns the "@data" fields and "@import" paths declared in comments of the template source.
+func (tm *TemplateManager) parseCodegenComments(src string) (fields []codegenField, imports []string, err error) {
+	left, right := tm.Config.Delims.get()
+	commentRegexp := regexp.MustCompile(regexp.QuoteMeta(left) + `-?\s*/\*([\s\S]*?)\*/\s*-?` + regexp.QuoteMeta(right))
+	for _, m := range commentRegexp.FindAllStringSubmatch(src, -1) {
+		for _, line := range strings.Split(m[1], "\n") {
+			words := strings.Fields(line)
+			if len(words) == 0 {
+				continue
+			}
+			switch words[0] {
+			case CodegenDataTag:
+				if len(words) < 3 || !codegenIdentRegexp.MatchString(words[1]) {
+					return nil, nil, fmt.Errorf("invalid %s: %q, should be: %s key type", CodegenDataTag, strings.TrimSpace(line), CodegenDataTag)
+				}
+				key := words[1]
+				fields = append(fields, codegenField{Key: key, Name: strings.ToUpper(key[:1]) + key[1:], Type: strings.Join(words[2:], " ")})
+			case CodegenImportTag:
+				for _, p := range words[1:] {
+					imports = append(imports, strings.Trim(p, `"`))
+				}
+			}
+		}
+	}
+	return fields, imports, nil
+}
+
+// GenerateCode returns the formatted Go code of main templates, see: "Code generation". Call Init(or Prepare) first.
+func (tm *TemplateManager) GenerateCode(options GenerateOptions) ([]byte, error) {
+	if options.Package == "" {
+		options.Package = "templates"
+	}
+	var errs []string
+	var templates []codegenTemplate
+	imports := make(map[string]bool)
+	idents := make(map[string]string)
+	for _, f := range tm.getMainFiles() {
+		basicName := tm.getBasicTemplateNameByFilePath(f)
+		te := NewTemplateEnvByParsing(string(TemplateModeContextPrefix) + basicName)
+		t := codegenTemplate{Name: te.StandardTemplateName(), BasicName: basicName, Ident: tm.getCodegenIdent(basicName)}
+		if other, ok := idents[t.Ident]; ok {
+			errs = append(errs, fmt.Sprintf("templates %q and %q have the same identifier %q", other, basicName, t.Ident))
+			continue
+		}
+		idents[t.Ident] = basicName
+
+		if options.RenderFuncs {
+			b, err := tm.readTemplateFile(f)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%q: %s", basicName, err))
+				continue
+			}
+			fields, declaredImports, err := tm.parseCodegenComments(string(b))
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%q: %s", basicName, err))
+				continue
+			}
+			if len(fields) > 0 {
+				if err := tm.checkCodegenFields(te, fields); err != nil {
+					errs = append(errs, fmt.Sprintf("%q: %s", basicName, err))
+					continue
+				}
+				t.Fields = fields
+				t.HasDataTypes = true
+				imports["io"] = true
+				imports["github.com/darkdarkfruit/templatemanager"] = true
+				for _, field := range fields {
+					for _, m := range codegenQualifierRegexp.FindAllStringSubmatch(field.Type, -1) {
+						importPath := m[1]
+						for _, p := range declaredImports {
+							if path.Base(p) == m[1] {
+								importPath = p
+							}
+						}
+						imports[importPath] = true
+					}
+				}
+			}
+		}
+		templates = append(templates, t)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("could not generate code of %d templates: %s", len(errs), strings.Join(errs, "; "))
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Ident < templates[j].Ident })
+
+	// standard packages(without "." in the first element) are imported first.
+	var stdImports, otherImports []string
+	for p := range imports {
+		if strings.Contains(strings.SplitN(p, "/", 2)[0], ".") {
+			otherImports = append(otherImports, p)
+		} else {
+			stdImports = append(stdImports, p)
+		}
+	}
+	sort.Strings(stdImports)
+	sort.Strings(otherImports)
+	buf := &bytes.Buffer{}
+	err := codegenSourceTemplate.Execute(buf, map[string]interface{}{
+		"Package": options.Package, "StdImports": stdImports, "Imports": otherImports, "Templates": templates,
+	})
+	if err != nil {
+		return nil, err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not format generated code: %s\n%s", err, buf.Bytes())
+	}
+	return code, nil
+}
+
+// checkCodegenFields checks that every field accessed by the template is declared, unused fields are logged.
+func (tm *TemplateManager) checkCodegenFields(te *TemplateEnv, fields []codegenField) error {
+	tpl, err := tm.tryParseTemplateUncached(te, tm.getFuncMap())
+	if err != nil {
+		return err
+	}
+	declared := make(map[string]bool)
+	names := make(map[string]string)
+	for _, field := range fields {
+		if other, ok := names[field.Name]; ok {
+			return fmt.Errorf("data keys %q and %q have the same field name %q", other, field.Key, field.Name)
+		}
+		names[field.Name] = field.Key
+		declared[field.Key] = true
+	}
+	accessed := make(map[string]bool)
+	var undeclared []string
+	for _, key := range GetDataKeys(getDataFields(tpl, tm.getEntryName(te))) {
+		accessed[key] = true
+		if !declared[key] {
+			undeclared = append(undeclared, key)
+		}
+	}
+	if len(undeclared) > 0 {
+		return fmt.Errorf("data keys %q are accessed but not declared by %s", undeclared, CodegenDataTag)
+	}
+	for _, field := range fields {
+		if !accessed[field.Key] {
+			log.Printf("Warning: data key %q of template %q is declared but never accessed", field.Key, te.StandardTemplateName())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package templatemanager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateManager_GenerateCode(t *testing.T) {
+	layout := `<title>{{ .title }}</title>{{ template "content" . }}`
+	tests := []struct {
+		name    string
+		files   map[string]string
+		delims  Delims
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "constants and render funcs",
+			files: map[string]string{
+				"main/blog/post.tpl.html": "{{/*\n@data title string\n@data post *models.Post\n@data now time.Time\n@import example.com/app/models\n*/}}" +
+					`{{ define "content" }}{{ .post.Body }}{{ .now.Year }}{{ end }}`,
+				"main/404.tpl.html": `{{ define "content" }}not found{{ end }}`,
+			},
+			want: []string{
+				"package views",
+				`"example.com/app/models"`,
+				`"time"`,
+				`TemplateBlogPost = "C->main/blog/post.tpl.html"`,
+				`TemplatePage404  = "C->main/404.tpl.html"`,
+				"type BlogPostData struct {",
+				"Post  *models.Post",
+				"func RenderBlogPost(tm *templatemanager.TemplateManager, w io.Writer, data BlogPostData) error {",
+				`"now":   data.Now,`,
+			},
+		},
+		{
+			name: "undeclared fields",
+			files: map[string]string{
+				"main/post.tpl.html": `{{/* @data title string */}}{{ define "content" }}{{ .body }}{{ end }}`,
+			},
+			wantErr: `data keys ["body"] are accessed but not declared`,
+		},
+		{
+			name: "invalid declaration",
+			files: map[string]string{
+				"main/post.tpl.html": `{{/* @data title */}}{{ define "content" }}{{ end }}`,
+			},
+			wantErr: `invalid @data: "@data title"`,
+		},
+		{
+			name: "same identifiers",
+			files: map[string]string{
+				"main/blog/post.tpl.html": `{{ define "content" }}{{ end }}`,
+				"main/blog_post.tpl.html": `{{ define "content" }}{{ end }}`,
+			},
+			wantErr: `have the same identifier "BlogPost"`,
+		},
+		{
+			name: "delims",
+			files: map[string]string{
+				"context/layout/layout.tpl.html": `<title>[[ .title ]]</title>[[ template "content" . ]]`,
+				"main/post.tpl.html":             `[[/* @data title string */]][[ define "content" ]]{{ .body }}[[ end ]]`,
+			},
+			delims: Delims{Left: "[[", Right: "]]"},
+			want:   []string{"type PostData struct {", "Title string"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := tt.files["context/layout/layout.tpl.html"]; !ok {
+				tt.files["context/layout/layout.tpl.html"] = layout
+			}
+			conf := newTestConfig(t, false, tt.files)
+			conf.Delims = tt.delims
+			tm := New(conf)
+			if err := tm.Prepare(); err != nil {
+				t.Fatal(err)
+			}
+			code, err := tm.GenerateCode(GenerateOptions{Package: "views", RenderFuncs: true})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("GenerateCode() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GenerateCode() error = %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(code), w) {
+					t.Errorf("GenerateCode() = \n%s\nwant contains: %q", code, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package templatemanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Loading config from files and environment variables.
+//
+// Keys are the json tags of TemplateConfig(FuncMap can only be set in go code). eg: config.yaml
+//
+//	dir_of_root: templates
+//	extensions: [".tpl.html", ".gohtml"]
+//	delims: {left: "[[", right: "]]"}
+//	is_debugging: false
+//	enable_minify_html: true
+//
+// Environment variables are "TEMPLATEMANAGER_" + upper case key, lists are separated by ",". eg:
+//
+//	TEMPLATEMANAGER_DIR_OF_ROOT=templates
+//	TEMPLATEMANAGER_EXTENSIONS=.tpl.html,.gohtml
+//	TEMPLATEMANAGER_DELIMS_LEFT=[[
+//	TEMPLATEMANAGER_IS_DEBUGGING=true
+//
+// Unknown keys(and unknown "TEMPLATEMANAGER_" variables) are errors.
+
+const EnvPrefix = "TEMPLATEMANAGER_"
+
+// LoadConfig returns NewDefaultConfig(false) overridden by the config file(skipped if filePath is "")
+// and then by environment variables.
+func LoadConfig(filePath string) (TemplateConfig, error) {
+	conf := NewDefaultConfig(false)
+	if filePath != "" {
+		if err := LoadConfigFile(&conf, filePath); err != nil {
+			return conf, err
+		}
+	}
+	if err := LoadConfigEnv(&conf, os.Environ()); err != nil {
+		return conf, err
+	}
+	return conf, nil
+}
+
+// LoadConfigFile overrides conf by the config file, the format is decided by extension: ".yaml", ".yml", ".toml" or ".json".
+func LoadConfigFile(conf *TemplateConfig, filePath string) error {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %q. err: %s", filePath, err)
+	}
+
+	var values map[string]interface{}
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".json":
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("unsupported config file: %q, extension should be one of: .yaml, .yml, .toml, .json", filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("could not parse config file: %q. err: %s", filePath, err)
+	}
+	if values != nil {
+		// yaml and toml are decoded as json, so all formats share the json tags and the strict checking.
+		if data, err = json.Marshal(values); err != nil {
+			return fmt.Errorf("could not parse config file: %q. err: %s", filePath, err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(conf); err != nil {
+		return fmt.Errorf("invalid config file: %q. err: %s", filePath, err)
+	}
+	return nil
+}
+
+// LoadConfigEnv overrides conf by the "TEMPLATEMANAGER_" variables of environ(eg: os.Environ()).
+// All problems are returned together as a *ConfigError.
+func LoadConfigEnv(conf *TemplateConfig, environ []string) error {
+	fields := make(map[string]reflect.Value)
+	getConfigEnvFields(reflect.ValueOf(conf).Elem(), EnvPrefix, fields)
+
+	var problems []string
+	for _, kv := range environ {
+		key, value := kv, ""
+		if i := strings.Index(kv, "="); i >= 0 {
+			key, value = kv[:i], kv[i+1:]
+		}
+		if !strings.HasPrefix(key, EnvPrefix) {
+			continue
+		}
+		field, ok := fields[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown environment variable %q", key))
+			continue
+		}
+		if err := setConfigField(field, value); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid environment variable %s=%q: %s", key, value, err))
+		}
+	}
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// getConfigEnvFields maps environment variable names to the fields of v(by json tags).
+func getConfigEnvFields(v reflect.Value, prefix string, fields map[string]reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		tag := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + strings.ToUpper(tag)
+		if v.Field(i).Kind() == reflect.Struct {
+			getConfigEnvFields(v.Field(i), key+"_", fields)
+			continue
+		}
+		fields[key] = v.Field(i)
+	}
+}
+
+func setConfigField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type: %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package templatemanager
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	want := NewDefaultConfig(false)
+	want.DirOfRoot = "web/templates"
+	want.Extensions = []string{".tpl.html", ".gohtml"}
+	want.Delims = Delims{Left: "[[", Right: "]]"}
+	want.MaxTenants = 10
+	want.IsDebugging = true
+	want.EnableMinifyHtml = true
+
+	tests := []struct {
+		name    string
+		file    string
+		src     string
+		wantErr string
+	}{
+		{"yaml", "config.yaml", `
+dir_of_root: web/templates
+extensions: [".tpl.html", ".gohtml"]
+delims: {left: "[[", right: "]]"}
+max_tenants: 10
+is_debugging: true
+enable_minify_html: true
+`, ""},
+		{"toml", "config.toml", `
+dir_of_root = "web/templates"
+extensions = [".tpl.html", ".gohtml"]
+max_tenants = 10
+is_debugging = true
+enable_minify_html = true
+
+[delims]
+left = "[["
+right = "]]"
+`, ""},
+		{"json", "config.json", `{
+	"dir_of_root": "web/templates",
+	"extensions": [".tpl.html", ".gohtml"],
+	"delims": {"left": "[[", "right": "]]"},
+	"max_tenants": 10,
+	"is_debugging": true,
+	"enable_minify_html": true
+}`, ""},
+		{"unknown key", "config.yaml", "dir_of_rot: web/templates\n", `unknown field "dir_of_rot"`},
+		{"unknown nested key", "config.toml", "[delims]\nleft = \"[[\"\nmiddle = \"|\"\n", `unknown field "middle"`},
+		{"func map", "config.json", `{"FuncMap": {}}`, `unknown field "FuncMap"`},
+		{"wrong type", "config.yaml", "max_tenants: many\n", "max_tenants"},
+		{"unsupported format", "config.ini", "dir_of_root = web/templates\n", "unsupported config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(filePath, []byte(tt.src), 0644); err != nil {
+				t.Fatal(err)
+			}
+			conf := NewDefaultConfig(false)
+			err := LoadConfigFile(&conf, filePath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadConfigFile() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfigFile() error = %v", err)
+			}
+			if !reflect.DeepEqual(conf, want) {
+				t.Errorf("LoadConfigFile() got = %#v, want %#v", conf, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	conf := NewDefaultConfig(true)
+	err := LoadConfigEnv(&conf, []string{
+		"HOME=/root",
+		"TEMPLATEMANAGER_DIR_OF_ROOT=web/templates",
+		"TEMPLATEMANAGER_EXTENSIONS=.tpl.html, .gohtml",
+		"TEMPLATEMANAGER_DELIMS_LEFT=[[",
+		"TEMPLATEMANAGER_IS_DEBUGGING=false",
+		"TEMPLATEMANAGER_ENABLE_MINIFY_HTML=1",
+		"TEMPLATEMANAGER_VERBOSE_LEVEL=2",
+	})
+	if err != nil {
+		t.Fatalf("LoadConfigEnv() error = %v", err)
+	}
+	want := NewDefaultConfig(false)
+	want.DirOfRoot = "web/templates"
+	want.Extensions = []string{".tpl.html", ".gohtml"}
+	want.Delims.Left = "[["
+	want.EnableMinifyHtml = true
+	want.VerboseLevel = 2
+	if !reflect.DeepEqual(conf, want) {
+		t.Errorf("LoadConfigEnv() got = %#v, want %#v", conf, want)
+	}
+
+	err = LoadConfigEnv(&conf, []string{
+		"TEMPLATEMANAGER_DIR_OF_ROT=web/templates",
+		"TEMPLATEMANAGER_FUNC_MAP=x",
+		"TEMPLATEMANAGER_MAX_TENANTS=many",
+		"TEMPLATEMANAGER_IS_DEBUGGING=yes",
+	})
+	configErr, ok := err.(*ConfigError)
+	if !ok || len(configErr.Problems) != 4 {
+		t.Fatalf("LoadConfigEnv() error = %v, want 4 problems", err)
+	}
+	for _, want := range []string{`unknown environment variable "TEMPLATEMANAGER_DIR_OF_ROT"`, `unknown environment variable "TEMPLATEMANAGER_FUNC_MAP"`, "TEMPLATEMANAGER_MAX_TENANTS", "TEMPLATEMANAGER_IS_DEBUGGING"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadConfigEnv() error = %v, want %q", err, want)
+		}
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "config.yml")
+	if err := os.WriteFile(filePath, []byte("dir_of_root: web/templates\nis_debugging: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEMPLATEMANAGER_IS_DEBUGGING", "false")
+	conf, err := LoadConfig(filePath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if conf.DirOfRoot != "web/templates" || conf.IsDebugging || conf.FuncMap == nil {
+		t.Errorf("LoadConfig() got = %#v", conf)
+	}
+}
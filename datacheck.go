@@ -0,0 +1,163 @@
+package templatemanager
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+)
+
+// Data check(debug mode only).
+//
+// If DataCheck is set, the data passed to ExecuteTemplate is compared with the keys accessed by the template(see: GetDataKeys):
+//   - Missing: keys accessed by the template but not in the data, eg: a handler forgets "now"
+//   - Unused: keys in the data(a map) but never accessed by the template
+//
+//	"log"     log missing and unused keys
+//	"error"   fail on missing keys, log unused keys
+//	"strict"  fail on missing and unused keys
+//
+// Keys of a struct are its fields and methods, unused keys of a struct are not checked.
+// A key accessed in any branch(eg: {{ if .user }}) should be present, nil is fine.
+
+const (
+	DataCheckLog    = "log"
+	DataCheckError  = "error"
+	DataCheckStrict = "strict"
+)
+
+// DataError is the result of checking data, see: "Data check".
+type DataError struct {
+	TemplateName string
+	Missing      []string
+	Unused       []string
+}
+
+func (e *DataError) Error() string {
+	s := fmt.Sprintf("data of template %q", e.TemplateName)
+	if len(e.Missing) > 0 {
+		s += fmt.Sprintf(" misses keys: %q", e.Missing)
+	}
+	if len(e.Unused) > 0 {
+		if len(e.Missing) > 0 {
+			s += ","
+		}
+		s += fmt.Sprintf(" has unused keys: %q", e.Unused)
+	}
+	return s
+}
+
+// CheckData compares the data with the keys accessed by the template, nil if nothing is missing or unused.
+// Unused keys are only checked if data is a map. It works in any mode, see: "Data check".
+func (tm *TemplateManager) CheckData(templateName string, data interface{}) (*DataError, error) {
+	fields, err := tm.GetDataFields(templateName)
+	if err != nil {
+		return nil, err
+	}
+	te, err := tm.NewTemplateEnv(templateName)
+	if err != nil {
+		return nil, err
+	}
+	return compareDataKeys(te.StandardTemplateName(), GetDataKeys(fields), data), nil
+}
+
+// getKeysOfData returns the sorted keys of the data, isMap is false for a struct. ok is false if data has no keys(eg: a slice).
+func getKeysOfData(data interface{}) (keys []string, isMap bool, ok bool) {
+	v := reflect.ValueOf(data)
+	if !v.IsValid() {
+		return nil, false, true
+	}
+	// methods of both the pointer and the value could be called.
+	t := v.Type()
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false, true
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false, false
+		}
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+		return keys, true, true
+	case reflect.Struct:
+		keys = getFieldNames(v.Type())
+		for i := 0; i < t.NumMethod(); i++ {
+			keys = append(keys, t.Method(i).Name)
+		}
+		sort.Strings(keys)
+		return keys, false, true
+	}
+	return nil, false, false
+}
+
+// getFieldNames returns the exported fields of the struct, including fields promoted from embedded structs.
+func getFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && ft.Kind() == reflect.Struct {
+			names = append(names, getFieldNames(ft)...)
+		}
+		if f.PkgPath == "" {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+func compareDataKeys(tplName string, accessedKeys []string, data interface{}) *DataError {
+	dataKeys, isMap, ok := getKeysOfData(data)
+	if !ok {
+		return nil
+	}
+	e := &DataError{TemplateName: tplName}
+	for _, key := range accessedKeys {
+		if !ContainsString(dataKeys, key) {
+			e.Missing = append(e.Missing, key)
+		}
+	}
+	if isMap {
+		for _, key := range dataKeys {
+			if !ContainsString(accessedKeys, key) {
+				e.Unused = append(e.Unused, key)
+			}
+		}
+	}
+	if len(e.Missing) == 0 && len(e.Unused) == 0 {
+		return nil
+	}
+	return e
+}
+
+// checkData checks the data before executing in debug mode, see: "Data check".
+// Fields collected while caching are used, the cached template might be executed(and escaped) concurrently.
+func (tm *TemplateManager) checkData(tplName string, data interface{}) error {
+	if !tm.Config.IsDebugging || tm.Config.DataCheck == "" {
+		return nil
+	}
+	fields, ok := tm.getCachedDataFields(tplName)
+	if !ok {
+		return nil
+	}
+	e := compareDataKeys(tplName, GetDataKeys(fields), data)
+	if e == nil {
+		return nil
+	}
+	switch {
+	case tm.Config.DataCheck == DataCheckStrict,
+		tm.Config.DataCheck == DataCheckError && len(e.Missing) > 0:
+		return e
+	}
+	log.Printf("Warning: %s", e)
+	return nil
+}
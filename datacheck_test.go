@@ -0,0 +1,120 @@
+package templatemanager
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type dataCheckBase struct {
+	Title string
+}
+
+type dataCheckPage struct {
+	dataCheckBase
+	Body string
+}
+
+func (dataCheckPage) Now() string { return "now" }
+
+var dataCheckTemplates = map[string]string{
+	"context/layout/layout.tpl.html": `<title>{{ .Title }}</title>{{ template "content" . }}`,
+	"main/page.tpl.html":             `{{ define "content" }}{{ .Body }}|{{ .Now }}{{ end }}`,
+}
+
+func TestTemplateManager_MissingKey(t *testing.T) {
+	tests := []struct {
+		missingKey string
+		want       string
+		wantErr    string
+	}{
+		{"", "<li> now: </li>", ""}, // html/template prints "" instead of "<no value>"
+		{"zero", "<li> now: </li>", ""},
+		{"error", "", `map has no entry for key "now"`},
+	}
+	for _, tt := range tests {
+		conf := NewDefaultConfig(false)
+		conf.MissingKey = tt.missingKey
+		tm := New(conf)
+		if err := tm.Init(true); err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		err := tm.ExecuteTemplate(out, "demo/demo1", map[string]string{"tplName": "demo1", "tplPath": ""})
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("MissingKey %q: ExecuteTemplate() error = %v, want %q", tt.missingKey, err, tt.wantErr)
+			}
+		} else if err != nil || !strings.Contains(out.String(), tt.want) {
+			t.Errorf("MissingKey %q: ExecuteTemplate() = %q, %v, want %q", tt.missingKey, out.String(), err, tt.want)
+		}
+	}
+
+	tm := NewDefault(false)
+	tm.Config.MissingKey = "panic"
+	if err := tm.Validate(); err == nil || !strings.Contains(err.Error(), `MissingKey "panic"`) {
+		t.Errorf("Validate() error = %v, want invalid MissingKey", err)
+	}
+}
+
+func TestTemplateManager_DataCheck(t *testing.T) {
+	full := map[string]interface{}{"Title": "t", "Body": "b", "Now": "n"}
+	missing := map[string]interface{}{"Title": "t", "Body": "b"}
+	unused := map[string]interface{}{"Title": "t", "Body": "b", "Now": "n", "User": "u"}
+	page := dataCheckPage{dataCheckBase{"t"}, "b"}
+	tests := []struct {
+		name        string
+		isDebugging bool
+		dataCheck   string
+		data        interface{}
+		wantErr     string
+	}{
+		{"full data", true, DataCheckStrict, full, ""},
+		{"struct with embedded fields and methods", true, DataCheckStrict, page, ""},
+		{"pointer of struct", true, DataCheckStrict, &page, ""},
+		{"missing keys are logged", true, DataCheckLog, missing, ""},
+		{"missing keys fail", true, DataCheckError, missing, `data of template "C->main/page.tpl.html" misses keys: ["Now"]`},
+		{"unused keys are logged", true, DataCheckError, unused, ""},
+		{"unused keys fail", true, DataCheckStrict, unused, `has unused keys: ["User"]`},
+		{"nil data", true, DataCheckError, nil, `misses keys: ["Body" "Now" "Title"]`},
+		{"not checked in production", false, DataCheckStrict, missing, ""},
+	}
+	for _, tt := range tests {
+		tm := newTestManager(t, tt.isDebugging, dataCheckTemplates, func(conf *TemplateConfig) { conf.DataCheck = tt.dataCheck })
+		err := tm.ExecuteTemplate(&bytes.Buffer{}, "main/page.tpl.html", tt.data)
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: ExecuteTemplate() error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	tm := newTestManager(t, false, dataCheckTemplates, nil)
+	got, err := tm.CheckData("main/page.tpl.html", unused)
+	want := &DataError{TemplateName: "C->main/page.tpl.html", Unused: []string{"User"}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckData() = %+v, %v, want %+v", got, err, want)
+	}
+	if got, err := tm.CheckData("main/page.tpl.html", full); err != nil || got != nil {
+		t.Errorf("CheckData() of full data = %+v, %v, want nil", got, err)
+	}
+}
+
+func TestTemplateManager_DataCheckConcurrently(t *testing.T) {
+	tm := newTestManager(t, true, dataCheckTemplates, func(conf *TemplateConfig) { conf.DataCheck = DataCheckError })
+	data := map[string]interface{}{"Title": "t", "Body": "b", "Now": "n"}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := tm.ExecuteTemplate(&bytes.Buffer{}, "main/page.tpl.html", data); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,161 @@
+package templatemanager
+
+import (
+	"bufio"
+	"log"
+	"path"
+	"strings"
+)
+
+// Template file discovery.
+//
+// A file listed by the loader is a template if:
+//   - its name ends with one of Extensions(or Extension if Extensions is empty). eg: [".tpl.html", ".gohtml", ".tmpl"]
+//   - it matches none of ExcludePatterns and the patterns of the ignore file(".tplignore" under root).
+//
+// A main template must also match one of IncludePatterns(if set), and must not be under DirOfContextRelativeToRoot
+// or a cascading context dir. If SkipDrafts is true, main templates with a path element starting with "_" are skipped,
+// eg: "main/_wip/a.tpl.html". Context templates(eg: "context/partial/_header.tpl.html") are never skipped.
+//
+// Patterns are slash separated globs(see: path.Match) relative to root, "**" matches any number of dirs:
+//
+//	"*.bak"            (no "/": matches the name of any file or dir)
+//	"main/admin"       (a dir matches everything under it)
+//	"main/**/old_*"
+//
+// The ignore file has one pattern per line, empty lines and lines starting with "#" are skipped.
+
+const DefaultIgnoreFileName = ".tplignore"
+
+// getExtensions returns the template extensions.
+func (tm *TemplateManager) getExtensions() []string {
+	if len(tm.Config.Extensions) > 0 {
+		return tm.Config.Extensions
+	}
+	return []string{tm.Config.Extension}
+}
+
+func (tm *TemplateManager) hasTemplateExtension(name string) bool {
+	for _, ext := range tm.getExtensions() {
+		if strings.HasSuffix(name, ext) && len(name) > len(ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// getIgnorePatterns reads the patterns of the ignore file by the loader, nil if the file does not exist.
+func (tm *TemplateManager) getIgnorePatterns() []string {
+	ignoreFileName := tm.Config.IgnoreFileName
+	if ignoreFileName == "" {
+		ignoreFileName = DefaultIgnoreFileName
+	}
+	f, err := tm.GetLoader().Open(ignoreFileName)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("Failed reading ignore file: %q. err: %s", ignoreFileName, err)
+	}
+	return patterns
+}
+
+// isTemplateFile checks if the file(basic name) is a template, see: "Template file discovery".
+func (tm *TemplateManager) isTemplateFile(basicName string, ignorePatterns []string) bool {
+	if !tm.hasTemplateExtension(basicName) {
+		return false
+	}
+	return !MatchAnyGlob(tm.Config.ExcludePatterns, basicName) && !MatchAnyGlob(ignorePatterns, basicName)
+}
+
+// isMainFile checks if the template file(basic name) is a main template.
+func (tm *TemplateManager) isMainFile(basicName string) bool {
+	if isInDir(basicName, tm.Config.DirOfContextRelativeToRoot) || basicName == path.Clean(tm.Config.FilePathOfLayoutRelativeToRoot) {
+		return false
+	}
+	if tm.isInCascadingContextDir(basicName) {
+		return false
+	}
+	if tm.Config.SkipDrafts && isDraft(basicName, tm.Config.CascadingContextDirName) {
+		return false
+	}
+	return len(tm.Config.IncludePatterns) == 0 || MatchAnyGlob(tm.Config.IncludePatterns, basicName)
+}
+
+// isDraft checks if any path element(except the cascading context dir) of the name starts with "_".
+func isDraft(name string, cascadingContextDirName string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, "_") && part != cascadingContextDirName {
+			return true
+		}
+	}
+	return false
+}
+
+// isInDir checks if name is under dir, both are relative to root. eg: isInDir("main/a.html", "./main/") is true
+func isInDir(name string, dir string) bool {
+	dir = strings.Trim(path.Clean("/"+dir), "/")
+	name = strings.Trim(path.Clean("/"+name), "/")
+	return dir == "" || strings.HasPrefix(name, dir+"/")
+}
+
+// MatchAnyGlob checks if the name matches any of the patterns, see: MatchGlob.
+func MatchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if MatchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchGlob checks if the slash separated name matches the pattern, see: "Template file discovery".
+func MatchGlob(pattern string, name string) bool {
+	pattern = strings.Trim(pattern, "/")
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	if pattern == "" {
+		return false
+	}
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		for _, part := range parts {
+			if ok, _ := path.Match(pattern, part); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return matchGlobParts(strings.Split(pattern, "/"), parts)
+}
+
+func matchGlobParts(patterns []string, parts []string) bool {
+	if len(patterns) == 0 {
+		// a matched dir matches everything under it.
+		return true
+	}
+	if patterns[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchGlobParts(patterns[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(patterns[0], parts[0]); !ok {
+		return false
+	}
+	return matchGlobParts(patterns[1:], parts[1:])
+}
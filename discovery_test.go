@@ -0,0 +1,153 @@
+package templatemanager
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.bak", "main/demo/a.tpl.html.bak", true},
+		{"*.bak", "main/demo/a.tpl.html", false},
+		{"admin", "main/admin/a.tpl.html", true},
+		{"main/admin", "main/admin/a.tpl.html", true},
+		{"main/admin/", "main/admin/a.tpl.html", true},
+		{"/main/admin", "main/admin/a.tpl.html", true},
+		{"main/admin", "main/demo/admin.tpl.html", false},
+		{"main/*/a.tpl.html", "main/demo/a.tpl.html", true},
+		{"main/**/old_*", "main/old_a.tpl.html", true},
+		{"main/**/old_*", "main/x/y/old_a.tpl.html", true},
+		{"main/**/old_*", "context/old_a.tpl.html", false},
+		{"**", "main/a.tpl.html", true},
+		{"", "main/a.tpl.html", false},
+		{"[", "main/a.tpl.html", false},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTemplateManager_FileDiscovery(t *testing.T) {
+	files := map[string]string{
+		".tplignore":                      "# comment\n\n*.bak\nmain/legacy\n",
+		"context/layout/layout.tpl.html":  `{{ template "content" . }}`,
+		"context/partial/ads.gohtml":      `{{ define "ads" }}ads{{ end }}`,
+		"context/partial/_wip.gohtml":     `{{ define "wip" }}wip{{ end }}`,
+		"main/home.tpl.html":              `{{ define "content" }}home{{ end }}`,
+		"main/blog/post.gohtml":           `{{ define "content" }}post{{ end }}`,
+		"main/blog/list.tmpl":             `{{ define "content" }}list{{ end }}`,
+		"main/blog/note.txt":              `not a template`,
+		"main/blog/post.gohtml.bak":       `{{ define "content" }}old post{{ end }}`,
+		"main/_drafts/new.tpl.html":       `{{ define "content" }}draft{{ end }}`,
+		"main/_draft.tpl.html":            `{{ define "content" }}draft{{ end }}`,
+		"main/legacy/old.tpl.html":        `{{ define "content" }}old{{ end }}`,
+		"main/admin/users.tpl.html":       `{{ define "content" }}users{{ end }}`,
+		"main/partials/nav.tpl.html":      `{{ define "nav" }}nav{{ end }}`,
+		"main/partials/footer.tpl.html":   `{{ define "footer" }}footer{{ end }}`,
+		"main/partials/_sidebar.tpl.html": `{{ define "sidebar" }}sidebar{{ end }}`,
+	}
+
+	tests := []struct {
+		name          string
+		modify        func(conf *TemplateConfig)
+		wantMainFiles []string
+		wantContext   []string
+	}{
+		{
+			name: "extensions, ignore file and drafts",
+			modify: func(conf *TemplateConfig) {
+				conf.Extensions = []string{".tpl.html", ".gohtml", ".tmpl"}
+				conf.SkipDrafts = true
+			},
+			wantMainFiles: []string{"main/admin/users.tpl.html", "main/blog/list.tmpl", "main/blog/post.gohtml", "main/home.tpl.html", "main/partials/footer.tpl.html", "main/partials/nav.tpl.html"},
+			wantContext:   []string{"context/layout/layout.tpl.html", "context/partial/_wip.gohtml", "context/partial/ads.gohtml"},
+		},
+		{
+			name: "single extension with drafts",
+			modify: func(conf *TemplateConfig) {
+				conf.Extension = ".gohtml"
+			},
+			wantMainFiles: []string{"main/blog/post.gohtml"},
+			wantContext:   []string{"context/partial/_wip.gohtml", "context/partial/ads.gohtml", "context/layout/layout.tpl.html"},
+		},
+		{
+			name: "include and exclude patterns",
+			modify: func(conf *TemplateConfig) {
+				conf.Extensions = []string{".tpl.html", ".gohtml", ".tmpl"}
+				conf.IncludePatterns = []string{"main/blog/**", "home.*"}
+				conf.ExcludePatterns = []string{"*.tmpl"}
+			},
+			wantMainFiles: []string{"main/blog/post.gohtml", "main/home.tpl.html"},
+			wantContext:   []string{"context/layout/layout.tpl.html", "context/partial/_wip.gohtml", "context/partial/ads.gohtml"},
+		},
+		{
+			name: "context dir inside the main dir",
+			modify: func(conf *TemplateConfig) {
+				conf.DirOfMainRelativeToRoot = "./main/"
+				conf.DirOfContextRelativeToRoot = "./main/partials/"
+				conf.FilePathOfLayoutRelativeToRoot = "context/layout/layout.tpl.html"
+				conf.IgnoreFileName = "none"
+				conf.SkipDrafts = true
+			},
+			wantMainFiles: []string{"main/admin/users.tpl.html", "main/home.tpl.html", "main/legacy/old.tpl.html"},
+			wantContext:   []string{"main/partials/_sidebar.tpl.html", "main/partials/footer.tpl.html", "main/partials/nav.tpl.html", "context/layout/layout.tpl.html"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := NewDefaultConfig(false)
+			conf.DirOfRoot = "templates"
+			tt.modify(&conf)
+			tm := New(conf)
+			tm.SetLoader(NewMemoryLoader(files))
+
+			var mainFiles, contextFiles []string
+			for _, f := range tm.getMainFiles() {
+				mainFiles = append(mainFiles, tm.getBasicTemplateNameByFilePath(f))
+			}
+			for _, f := range tm.getContextFiles() {
+				contextFiles = append(contextFiles, tm.getBasicTemplateNameByFilePath(f))
+			}
+			if !reflect.DeepEqual(mainFiles, tt.wantMainFiles) {
+				t.Errorf("getMainFiles() = %q, want %q", mainFiles, tt.wantMainFiles)
+			}
+			if !reflect.DeepEqual(contextFiles, tt.wantContext) {
+				t.Errorf("getContextFiles() = %q, want %q", contextFiles, tt.wantContext)
+			}
+		})
+	}
+}
+
+func TestTemplateManager_UnderscoreContextPartial(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "templates")
+	writeTemplateFiles(t, root, map[string]string{
+		"context/layout/layout.tpl.html":   `<html>{{ template "header" . }}|{{ template "content" . }}</html>`,
+		"context/partial/_header.tpl.html": `{{ define "header" }}header{{ end }}`,
+		"main/home.tpl.html":               `{{ define "content" }}home{{ end }}`,
+		"main/_wip.tpl.html":               `{{ define "content" }}wip{{ end }}`,
+	})
+	for _, skipDrafts := range []bool{false, true} {
+		conf := NewDefaultConfig(false)
+		conf.DirOfRoot = root
+		conf.SkipDrafts = skipDrafts
+		tm := New(conf)
+		if err := tm.Init(true); err != nil {
+			t.Fatal(err)
+		}
+		out := &bytes.Buffer{}
+		if err := tm.ExecuteTemplate(out, "home", nil); err != nil || out.String() != "<html>header|home</html>" {
+			t.Errorf("SkipDrafts: %v, ExecuteTemplate() got = %q, err = %v", skipDrafts, out.String(), err)
+		}
+		if _, err := tm.NewTemplateEnv("_wip"); (err != nil) != skipDrafts {
+			t.Errorf("SkipDrafts: %v, NewTemplateEnv(%q) err = %v", skipDrafts, "_wip", err)
+		}
+	}
+}
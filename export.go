@@ -0,0 +1,274 @@
+package templatemanager
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Static site generation.
+//
+// Export renders main templates(ContextMode) to outDir, mirroring the dirs under DirOfMainRelativeToRoot:
+//
+//	main/index.tpl.html       -> index.html
+//	main/demo/demo1.tpl.html  -> demo/demo1.html  (PrettyURLs: demo/demo1/index.html)
+//
+// Data of a page is returned by the DataProvider, default is SidecarData: a json/yaml file next to the template,
+// eg: "main/demo/demo1.tpl.json" or "main/demo/demo1.json" for "main/demo/demo1.tpl.html".
+
+// DataProvider returns the data of the page. name is the main template(path relative to root).
+type DataProvider func(name string) (interface{}, error)
+
+// SidecarDataExtensions are the extensions of sidecar data files, the first existing one is used.
+var SidecarDataExtensions = []string{".json", ".yaml", ".yml"}
+
+const (
+	SidecarScenariosKey = "scenarios"
+	DefaultScenario     = "default"
+)
+
+// exportManifestSuffix is appended to outDir for the default manifest. eg: "out" -> "out.templatemanager-export.json"
+const exportManifestSuffix = ".templatemanager-export.json"
+
+type ExportOptions struct {
+	Patterns    []string // glob patterns of main templates(relative to root) to export, empty: all. eg: ["main/blog/**"]
+	Workers     int      // number of pages rendered in parallel, 0: runtime.NumCPU()
+	PrettyURLs  bool     // true: "demo/demo1/index.html"; false: "demo/demo1.html"
+	Incremental bool     // true: skip pages whose templates and data are not changed since the last export
+	// file recording hashes of exported pages, default: outDir + ".templatemanager-export.json"(outside outDir, so it is not published)
+	ManifestPath string
+}
+
+type ExportResult struct {
+	Rendered []string // output files(relative to outDir)
+	Skipped  []string // output files not changed(Incremental)
+}
+
+// SidecarData is the DataProvider which reads the sidecar data file of the page, nil if there is none.
+// The "default" scenario(or the first one) is used if the file has scenarios, see: SidecarScenarios.
+func (tm *TemplateManager) SidecarData(name string) (interface{}, error) {
+	scenarios, err := tm.SidecarScenarios(name)
+	if err != nil || len(scenarios) == 0 {
+		return nil, err
+	}
+	if data, ok := scenarios[DefaultScenario]; ok {
+		return data, nil
+	}
+	return scenarios[GetScenarioNames(scenarios)[0]], nil
+}
+
+// SidecarScenarios reads the sidecar data file of the page(eg: "main/demo/demo1.tpl.json" or "main/demo/demo1.json"),
+// nil if there is none. A file with a top level "scenarios" key has multiple named data:
+//
+//	{"scenarios": {"default": {"name": "x"}, "empty": {}}}
+//
+// otherwise the whole file is the "default" scenario.
+func (tm *TemplateManager) SidecarScenarios(name string) (map[string]interface{}, error) {
+	loader := tm.GetLoader()
+	for _, ext := range SidecarDataExtensions {
+		for _, dataName := range []string{strings.TrimSuffix(name, path.Ext(name)) + ext, trimAllExt(name) + ext} {
+			r, err := loader.Open(dataName)
+			if err != nil {
+				continue
+			}
+			b, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				return nil, err
+			}
+			var data interface{}
+			if ext == ".json" {
+				err = json.Unmarshal(b, &data)
+			} else {
+				err = yaml.Unmarshal(b, &data)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not parse data file: %q. err: %s", dataName, err)
+			}
+			if m, ok := data.(map[string]interface{}); ok && len(m) == 1 {
+				if scenarios, ok := m[SidecarScenariosKey].(map[string]interface{}); ok {
+					return scenarios, nil
+				}
+			}
+			return map[string]interface{}{DefaultScenario: data}, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetScenarioNames returns the sorted names of scenarios.
+func GetScenarioNames(scenarios map[string]interface{}) []string {
+	var names []string
+	for name := range scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetExportPath returns the output file(relative to outDir) of the main template.
+func (tm *TemplateManager) GetExportPath(name string, prettyURLs bool) string {
+	mainDir := strings.Trim(path.Clean("/"+tm.Config.DirOfMainRelativeToRoot), "/")
+	p := strings.TrimPrefix(trimAllExt(name), mainDir+"/")
+	if prettyURLs && path.Base(p) != "index" {
+		return path.Join(p, "index.html")
+	}
+	return p + ".html"
+}
+
+// Export renders main templates to outDir, see: "Static site generation". Call Init(or Prepare) first.
+// dataProvider nil means SidecarData. Errors of pages are returned together, other pages are still rendered.
+func (tm *TemplateManager) Export(outDir string, dataProvider DataProvider, options ExportOptions) (ExportResult, error) {
+	var result ExportResult
+	if dataProvider == nil {
+		dataProvider = tm.SidecarData
+	}
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	var names []string
+	for _, f := range tm.getMainFiles() {
+		name := tm.getBasicTemplateNameByFilePath(f)
+		if len(options.Patterns) == 0 || MatchAnyGlob(options.Patterns, name) {
+			names = append(names, name)
+		}
+	}
+	// eg: "main/a.tpl.html" and "main/a.tpl" are both exported to "a.html"
+	namesOfExportPaths := make(map[string]string)
+	for _, name := range names {
+		exportPath := tm.GetExportPath(name, options.PrettyURLs)
+		if other, ok := namesOfExportPaths[exportPath]; ok {
+			return result, fmt.Errorf("main templates %q and %q are exported to the same file: %q", other, name, exportPath)
+		}
+		namesOfExportPaths[exportPath] = name
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return result, err
+	}
+	// the manifest records hashes of exported pages, it is kept even if not Incremental.
+	manifest := make(map[string]string)
+	manifestPath := options.ManifestPath
+	if manifestPath == "" {
+		absOutDir, err := filepath.Abs(outDir)
+		if err != nil {
+			return result, err
+		}
+		manifestPath = absOutDir + exportManifestSuffix
+	}
+	if b, err := ioutil.ReadFile(manifestPath); err == nil {
+		json.Unmarshal(b, &manifest)
+	}
+
+	var mutex sync.Mutex
+	var errs []string
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range queue {
+				exportPath := tm.GetExportPath(name, options.PrettyURLs)
+				mutex.Lock()
+				lastHash := manifest[exportPath]
+				mutex.Unlock()
+				if !options.Incremental {
+					lastHash = ""
+				}
+				rendered, hash, err := tm.exportPage(outDir, exportPath, name, dataProvider, lastHash)
+				mutex.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("%q: %s", name, err))
+				} else if rendered {
+					result.Rendered = append(result.Rendered, exportPath)
+					manifest[exportPath] = hash
+				} else {
+					result.Skipped = append(result.Skipped, exportPath)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		queue <- name
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.Strings(result.Rendered)
+	sort.Strings(result.Skipped)
+	sort.Strings(errs)
+	if b, err := json.MarshalIndent(manifest, "", "  "); err == nil {
+		if err := ioutil.WriteFile(manifestPath, b, 0644); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return result, fmt.Errorf("failed exporting %d pages: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return result, nil
+}
+
+// exportPage renders the page to outDir/exportPath, it returns false if the hash is lastHash(not changed since the last export).
+func (tm *TemplateManager) exportPage(outDir, exportPath, name string, dataProvider DataProvider, lastHash string) (rendered bool, hash string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			rendered, err = false, fmt.Errorf("%v", r)
+		}
+	}()
+	data, err := dataProvider(name)
+	if err != nil {
+		return false, "", err
+	}
+	te, err := tm.NewTemplateEnv(string(TemplateModeContextPrefix) + name)
+	if err != nil {
+		return false, "", err
+	}
+
+	// hash of the template files and the data
+	h := sha256.New()
+	for _, f := range tm.getFilesForParsing(te) {
+		b, err := tm.readTemplateFile(f)
+		if err != nil {
+			return false, "", err
+		}
+		fmt.Fprintf(h, "%s\n%d\n", f, len(b))
+		h.Write(b)
+	}
+	if b, err := json.Marshal(data); err == nil {
+		h.Write(b)
+		hash = hex.EncodeToString(h.Sum(nil))
+	}
+	// hash is "" if data could not be hashed, then the page is always rendered.
+	outPath := filepath.Join(outDir, filepath.FromSlash(exportPath))
+	if _, err := os.Stat(outPath); err == nil && hash != "" && lastHash == hash {
+		return false, hash, nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := tm.ExecuteTemplate(buf, te.StandardTemplateName(), data); err != nil {
+		return false, "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return false, "", err
+	}
+	if err := ioutil.WriteFile(outPath, buf.Bytes(), 0644); err != nil {
+		return false, "", err
+	}
+	return true, hash, nil
+}
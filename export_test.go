@@ -0,0 +1,111 @@
+package templatemanager
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTemplateManager_Export(t *testing.T) {
+	conf := newTestConfig(t, false, map[string]string{
+		"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`,
+		"main/index.tpl.html":            `{{ define "content" }}index{{ end }}`,
+		"main/demo/demo1.tpl.html":       `{{ define "content" }}{{ .name }}{{ end }}`,
+		"main/demo/demo1.json":           `{"name": "demo1 from json"}`,
+		"main/blog/post.tpl.html":        `{{ define "content" }}{{ .title }}{{ end }}`,
+		"main/blog/post.yaml":            "title: post from yaml\n",
+	})
+	tm := New(conf)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+
+	readOutput := func(outDir, name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(outDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	outDir := filepath.Join(t.TempDir(), "out")
+	result, err := tm.Export(outDir, nil, ExportOptions{Workers: 2, Incremental: true})
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	// the manifest is not in outDir.
+	if _, err := os.Stat(outDir + ".templatemanager-export.json"); err != nil {
+		t.Errorf("manifest error = %v", err)
+	}
+	if files, _ := ioutil.ReadDir(outDir); len(files) != 3 {
+		t.Errorf("files of outDir = %d, want 3", len(files))
+	}
+	wantRendered := []string{"blog/post.html", "demo/demo1.html", "index.html"}
+	if !reflect.DeepEqual(result.Rendered, wantRendered) || len(result.Skipped) != 0 {
+		t.Errorf("Export() = %+v, want rendered %q", result, wantRendered)
+	}
+	for name, want := range map[string]string{
+		"index.html":      "<html>index</html>",
+		"demo/demo1.html": "<html>demo1 from json</html>",
+		"blog/post.html":  "<html>post from yaml</html>",
+	} {
+		if got := readOutput(outDir, name); got != want {
+			t.Errorf("output of %q = %q, want %q", name, got, want)
+		}
+	}
+
+	// incremental: only the page whose data changed is rendered again.
+	if err := os.WriteFile(filepath.Join(conf.DirOfRoot, "main/demo/demo1.json"), []byte(`{"name": "demo1 changed"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err = tm.Export(outDir, nil, ExportOptions{Incremental: true})
+	if err != nil || !reflect.DeepEqual(result.Rendered, []string{"demo/demo1.html"}) || !reflect.DeepEqual(result.Skipped, []string{"blog/post.html", "index.html"}) {
+		t.Errorf("Export() = %+v, err = %v", result, err)
+	}
+	if got := readOutput(outDir, "demo/demo1.html"); got != "<html>demo1 changed</html>" {
+		t.Errorf("output of demo1 = %q", got)
+	}
+
+	// pretty urls, patterns, a go callback and a manifest path.
+	outDir = filepath.Join(t.TempDir(), "pretty")
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	provider := func(name string) (interface{}, error) {
+		return map[string]string{"name": "callback " + name}, nil
+	}
+	result, err = tm.Export(outDir, provider, ExportOptions{PrettyURLs: true, Patterns: []string{"main/demo/**", "index.*"}, ManifestPath: manifestPath})
+	if err != nil || !reflect.DeepEqual(result.Rendered, []string{"demo/demo1/index.html", "index.html"}) {
+		t.Errorf("Export() = %+v, err = %v", result, err)
+	}
+	if _, err := os.Stat(manifestPath); err != nil {
+		t.Errorf("manifest error = %v", err)
+	}
+	if got := readOutput(outDir, "demo/demo1/index.html"); got != "<html>callback main/demo/demo1.tpl.html</html>" {
+		t.Errorf("output of demo1 = %q", got)
+	}
+
+	// errors of a page do not stop other pages.
+	writeTemplateFiles(t, conf.DirOfRoot, map[string]string{"main/broken.tpl.html": `{{ define "content" }}{{ .name.x }}{{ end }}`})
+	tm = New(conf)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+	result, err = tm.Export(t.TempDir(), func(name string) (interface{}, error) { return map[string]int{"name": 1}, nil }, ExportOptions{})
+	if err == nil || !strings.Contains(err.Error(), "failed exporting 1 pages") || len(result.Rendered) != 3 {
+		t.Errorf("Export() = %+v, err = %v", result, err)
+	}
+
+	// main templates exported to the same file.
+	writeTemplateFiles(t, conf.DirOfRoot, map[string]string{"main/index.v2.tpl.html": `{{ define "content" }}index v2{{ end }}`})
+	tm = New(conf)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+	outDir = t.TempDir()
+	_, err = tm.Export(outDir, nil, ExportOptions{Patterns: []string{"main/index*"}})
+	if want := `main templates "main/index.tpl.html" and "main/index.v2.tpl.html" are exported to the same file: "index.html"`; err == nil || err.Error() != want {
+		t.Errorf("Export() error = %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,245 @@
+package templatemanager
+
+import (
+	"html/template"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// Data fields.
+//
+// GetDataFields walks the parse trees from the entry(including templates reached by {{ template }}),
+// and returns the paths of fields accessed from the data, eg:
+//
+//	{{ .title }}                        "title"
+//	{{ with .user }}{{ .name }}{{ end }} "user", "user.name"
+//	{{ range .items }}{{ .id }}{{ end }} "items", "items[].id"
+//	{{ template "nav" .menu }}          "menu", and "menu.x" for {{ .x }} in "nav"
+//
+// Fields under a dot which could not be resolved(eg: {{ with index .a 0 }}) are skipped,
+// and a recursive template is walked only once.
+//
+// Keys of the data are the first elements of the paths, see: GetDataKeys. eg: handler data could be checked in tests
+//
+//	fields, _ := tplMgr.GetDataFields("main/demo/demo1.tpl.html")
+//	for _, key := range templatemanager.GetDataKeys(fields) { if _, ok := data[key]; !ok { t.Errorf(...) } }
+
+// dataScope is the dot and variables while walking, path "" is the data itself.
+type dataScope struct {
+	dot   string
+	known bool                   // false: the dot could not be resolved
+	vars  map[string]dataVarPath // variables, "$" is the data
+}
+
+type dataVarPath struct {
+	path  string
+	known bool
+}
+
+func (s dataScope) withDot(dot string, known bool) dataScope {
+	vars := make(map[string]dataVarPath, len(s.vars))
+	for k, v := range s.vars {
+		vars[k] = v
+	}
+	return dataScope{dot: dot, known: known, vars: vars}
+}
+
+func joinDataPath(prefix string, idents []string) string {
+	if prefix == "" {
+		return strings.Join(idents, ".")
+	}
+	if len(idents) == 0 {
+		return prefix
+	}
+	return prefix + "." + strings.Join(idents, ".")
+}
+
+type dataFieldWalker struct {
+	tpl     *template.Template
+	fields  map[string]bool
+	visited map[string]bool
+	calling map[string]bool // templates being walked
+}
+
+// GetDataFields returns the sorted paths of fields accessed from the data by the template, see: "Data fields".
+// Fields of cached templates are collected while parsing, other templates are parsed without caching.
+func (tm *TemplateManager) GetDataFields(templateName string) ([]string, error) {
+	te, err := tm.NewTemplateEnv(templateName)
+	if err != nil {
+		return nil, err
+	}
+	if fields, ok := tm.getCachedDataFields(te.StandardTemplateName()); ok {
+		return fields, nil
+	}
+	tpl, err := tm.tryParseTemplateUncached(te, tm.getFuncMap())
+	if err != nil {
+		return nil, err
+	}
+	return getDataFields(tpl, tm.getEntryName(te)), nil
+}
+
+// getCachedDataFields returns a copy of the data fields of the cached template.
+func (tm *TemplateManager) getCachedDataFields(tplName string) ([]string, bool) {
+	tm.templateMutex.RLock()
+	defer tm.templateMutex.RUnlock()
+	fields, ok := tm.dataFields[tplName]
+	return append([]string(nil), fields...), ok
+}
+
+// GetMapOfTemplateNameToDataFields returns the data fields of every cached template, see: GetDataFields.
+func (tm *TemplateManager) GetMapOfTemplateNameToDataFields() map[string][]string {
+	tm.templateMutex.RLock()
+	defer tm.templateMutex.RUnlock()
+	m := make(map[string][]string, len(tm.dataFields))
+	for tplName, fields := range tm.dataFields {
+		m[tplName] = append([]string(nil), fields...)
+	}
+	return m
+}
+
+// GetDataKeys returns the sorted keys of the data(the first elements) of the paths. eg: ["user", "user.name", "items[].id"] -> ["items", "user"]
+func GetDataKeys(fields []string) []string {
+	var keys []string
+	for _, f := range fields {
+		key := strings.TrimSuffix(strings.SplitN(f, ".", 2)[0], "[]")
+		if key != "" && !ContainsString(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// getDataFields returns the sorted paths of fields accessed from the data, see: "Data fields".
+func getDataFields(tpl *template.Template, entry string) []string {
+	w := &dataFieldWalker{tpl: tpl, fields: make(map[string]bool), visited: make(map[string]bool), calling: make(map[string]bool)}
+	w.walkTemplate(entry, "", true)
+	var fields []string
+	for f := range w.fields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func (w *dataFieldWalker) walkTemplate(name string, dot string, known bool) {
+	key := name + "\x00" + dot
+	if !known {
+		key = name + "\x00?"
+	}
+	// a recursive template is walked once, the path would grow forever.
+	if w.visited[key] || w.calling[name] {
+		return
+	}
+	w.visited[key] = true
+	t := w.tpl.Lookup(name)
+	if t == nil || t.Tree == nil {
+		return
+	}
+	w.calling[name] = true
+	defer delete(w.calling, name)
+	scope := dataScope{dot: dot, known: known, vars: map[string]dataVarPath{"$": {dot, known}}}
+	w.walkNode(t.Tree.Root, scope)
+}
+
+func (w *dataFieldWalker) walkNode(node parse.Node, scope dataScope) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			w.walkNode(child, scope)
+		}
+	case *parse.ActionNode:
+		w.walkPipe(n.Pipe, scope)
+		if n.Pipe != nil {
+			p, ok := w.pipePath(n.Pipe, scope)
+			for _, v := range n.Pipe.Decl {
+				scope.vars[v.Ident[0]] = dataVarPath{p, ok}
+			}
+		}
+	case *parse.IfNode:
+		w.walkPipe(n.Pipe, scope)
+		w.walkNode(n.List, scope.withDot(scope.dot, scope.known))
+		w.walkNode(n.ElseList, scope.withDot(scope.dot, scope.known))
+	case *parse.WithNode:
+		w.walkPipe(n.Pipe, scope)
+		p, ok := w.pipePath(n.Pipe, scope)
+		inner := scope.withDot(p, ok)
+		for _, v := range n.Pipe.Decl {
+			inner.vars[v.Ident[0]] = dataVarPath{p, ok}
+		}
+		w.walkNode(n.List, inner)
+		w.walkNode(n.ElseList, scope.withDot(scope.dot, scope.known))
+	case *parse.RangeNode:
+		w.walkPipe(n.Pipe, scope)
+		p, ok := w.pipePath(n.Pipe, scope)
+		inner := scope.withDot(p+"[]", ok)
+		switch len(n.Pipe.Decl) {
+		case 1:
+			inner.vars[n.Pipe.Decl[0].Ident[0]] = dataVarPath{p + "[]", ok}
+		case 2:
+			inner.vars[n.Pipe.Decl[0].Ident[0]] = dataVarPath{}
+			inner.vars[n.Pipe.Decl[1].Ident[0]] = dataVarPath{p + "[]", ok}
+		}
+		w.walkNode(n.List, inner)
+		w.walkNode(n.ElseList, scope.withDot(scope.dot, scope.known))
+	case *parse.TemplateNode:
+		if n.Pipe == nil {
+			// the data of the template is nil
+			w.walkTemplate(n.Name, "", false)
+			return
+		}
+		w.walkPipe(n.Pipe, scope)
+		p, ok := w.pipePath(n.Pipe, scope)
+		w.walkTemplate(n.Name, p, ok)
+	}
+}
+
+func (w *dataFieldWalker) walkPipe(pipe *parse.PipeNode, scope dataScope) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			w.walkArg(arg, scope)
+		}
+	}
+}
+
+func (w *dataFieldWalker) walkArg(arg parse.Node, scope dataScope) {
+	switch n := arg.(type) {
+	case *parse.FieldNode:
+		if scope.known {
+			w.fields[joinDataPath(scope.dot, n.Ident)] = true
+		}
+	case *parse.VariableNode:
+		if v, ok := scope.vars[n.Ident[0]]; ok && v.known && len(n.Ident) > 1 {
+			w.fields[joinDataPath(v.path, n.Ident[1:])] = true
+		}
+	case *parse.PipeNode:
+		w.walkPipe(n, scope)
+	case *parse.ChainNode:
+		w.walkArg(n.Node, scope)
+	}
+}
+
+// pipePath returns the path of the pipeline if it is a single field(or dot, or variable), eg: {{ with .user }}
+func (w *dataFieldWalker) pipePath(pipe *parse.PipeNode, scope dataScope) (string, bool) {
+	if pipe == nil || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	switch n := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return scope.dot, scope.known
+	case *parse.FieldNode:
+		return joinDataPath(scope.dot, n.Ident), scope.known
+	case *parse.VariableNode:
+		if v, ok := scope.vars[n.Ident[0]]; ok && v.known {
+			return joinDataPath(v.path, n.Ident[1:]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,76 @@
+package templatemanager
+
+import (
+	"html/template"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_getDataFields(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"fields", `{{ .title }}{{ .user.name }}{{ printf "%s" .tip }}`, []string{"tip", "title", "user.name"}},
+		{"with", `{{ with .user }}{{ .name }}{{ else }}{{ .guest }}{{ end }}`, []string{"guest", "user", "user.name"}},
+		{"range", `{{ range .items }}{{ .id }}{{ $.title }}{{ end }}`, []string{"items", "items[].id", "title"}},
+		{"range with variables", `{{ range $i, $item := .items }}{{ $item.id }}{{ end }}`, []string{"items", "items[].id"}},
+		{"variables", `{{ $u := .user }}{{ $u.name }}{{ if $u }}{{ $.title }}{{ end }}`, []string{"title", "user", "user.name"}},
+		{"template", `{{ template "nav" .menu }}{{ template "footer" }}{{ define "nav" }}{{ .current }}{{ end }}{{ define "footer" }}{{ .year }}{{ end }}`, []string{"menu", "menu.current"}},
+		{"recursive template", `{{ template "tree" . }}{{ define "tree" }}{{ .name }}{{ range .children }}{{ template "tree" . }}{{ end }}{{ end }}`, []string{"children", "name"}},
+		{"unresolved dot", `{{ with index .items 0 }}{{ .id }}{{ end }}`, []string{"items"}},
+		{"dot", `{{ . }}`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tpl := template.Must(template.New("entry").Parse(tt.src))
+			if got := getDataFields(tpl, "entry"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getDataFields() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateManager_GetDataFields(t *testing.T) {
+	tm := gTplMgr
+	cached, _ := tm.GetTemplate("C->main/demo/demo1.tpl.html")
+	tests := []struct {
+		templateName string
+		want         []string
+		wantErr      bool
+	}{
+		{"demo/demo1", []string{"now", "tplName", "tplPath"}, false},
+		{"F->demo/demo1", nil, false}, // the entry is the file itself, which only defines templates
+		{"main/none.tpl.html", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := tm.GetDataFields(tt.templateName)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetDataFields(%q) = %q, %v, want %q", tt.templateName, got, err, tt.want)
+		}
+	}
+	if got, want := GetDataKeys([]string{"menu", "menu[].url", "post.body", "[].id"}), []string{"menu", "post"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetDataKeys() = %q, want %q", got, want)
+	}
+	if report := tm.Report(); !strings.Contains(report, `"C->main/demo/demo1.tpl.html" -> ["now" "tplName" "tplPath"]`) {
+		t.Errorf("Report() = %s, want data fields", report)
+	}
+	// reading data fields does not replace cached templates.
+	if tpl, _ := tm.GetTemplate("C->main/demo/demo1.tpl.html"); tpl != cached {
+		t.Errorf("cached template should not be changed by GetDataFields")
+	}
+
+	// templates which are not cached yet are parsed without caching.
+	tm = NewDefault(false)
+	if err := tm.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := tm.GetDataFields("demo/demo2"); err != nil || !reflect.DeepEqual(got, []string{"now", "tplName", "tplPath"}) {
+		t.Errorf("GetDataFields() of a lazy template = %q, %v", got, err)
+	}
+	if _, ok := tm.GetTemplate("C->main/demo/demo2.tpl.html"); ok {
+		t.Errorf("GetDataFields() should not cache the template")
+	}
+}
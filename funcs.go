@@ -0,0 +1,212 @@
+package templatemanager
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Request funcs.
+//
+// ExecuteTemplateWithFuncs overrides functions of FuncMap for one execution, eg: request-scoped helpers
+// like csrfToken, currentUser or t(translation). Placeholders must be in FuncMap, templates are parsed with them:
+//
+//	tplConfig.FuncMap["csrfToken"] = func() string { return "" }
+//	tplConfig.EnableRequestFuncs = true
+//
+//	tplMgr.ExecuteTemplateWithFuncs(w, "main/demo/demo1.tpl.html", data, template.FuncMap{
+//		"csrfToken": func() string { return csrf.Token(r) },
+//	})
+//
+// An executed html/template could not be cloned, so an unexecuted clone of every template is kept if EnableRequestFuncs is true.
+// Executions get clones(of the clone) from a pool, whose functions are replaced by Funcs, then reset to the placeholders.
+// Templates rendered by "include" are executed with the funcs too.
+
+type requestFuncsTemplate struct {
+	source   *template.Template // the cached template
+	pristine *template.Template // never executed
+	pool     sync.Pool
+}
+
+// setRequestFuncsTemplate keeps an unexecuted clone of the template, it is called before the template is cached.
+func (tm *TemplateManager) setRequestFuncsTemplate(tplName string, tpl *template.Template) {
+	if !tm.Config.EnableRequestFuncs {
+		return
+	}
+	pristine, err := tpl.Clone()
+	if err != nil {
+		panic(fmt.Sprintf("could not clone template: %q. err: %s", tplName, err))
+	}
+	t := &requestFuncsTemplate{source: tpl, pristine: pristine}
+	t.pool.New = func() interface{} {
+		return template.Must(t.pristine.Clone())
+	}
+	tm.requestFuncsMutex.Lock()
+	defer tm.requestFuncsMutex.Unlock()
+	if tm.requestFuncsTemplates == nil {
+		tm.requestFuncsTemplates = make(map[string]*requestFuncsTemplate)
+	}
+	tm.requestFuncsTemplates[tplName] = t
+}
+
+// getRequestFuncsTemplate returns a clone of the cached template tpl with funcs, release puts it back to the pool.
+func (tm *TemplateManager) getRequestFuncsTemplate(tplName string, tpl *template.Template, funcs template.FuncMap) (clone *template.Template, release func(), err error) {
+	if !tm.Config.EnableRequestFuncs {
+		return nil, nil, fmt.Errorf("could not execute template %q with funcs: EnableRequestFuncs is false", tplName)
+	}
+	funcMap := tm.getFuncMap()
+	placeholders := template.FuncMap{"include": funcMap["include"]}
+	for name := range funcs {
+		if name == "include" {
+			return nil, nil, fmt.Errorf("function %q is a builtin function", name)
+		}
+		placeholder, ok := funcMap[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("function %q is not in FuncMap, a placeholder should be added before parsing", name)
+		}
+		placeholders[name] = placeholder
+	}
+	tm.requestFuncsMutex.RLock()
+	t, ok := tm.requestFuncsTemplates[tplName]
+	tm.requestFuncsMutex.RUnlock()
+	if !ok || t.source != tpl {
+		return nil, nil, fmt.Errorf("could not find the unexecuted clone of template: %q", tplName)
+	}
+	clone = t.pool.Get().(*template.Template)
+	clone.Funcs(funcs)
+	clone.Funcs(template.FuncMap{"include": func(name string, data interface{}) (template.HTML, error) {
+		buf := new(bytes.Buffer)
+		err := tm.executeTemplate(buf, name, data, funcs)
+		return template.HTML(buf.String()), err
+	}})
+	release = func() {
+		// functions of the request should not be kept in the pool.
+		clone.Funcs(placeholders)
+		t.pool.Put(clone)
+	}
+	return clone, release, nil
+}
+
+// ExecuteTemplateWithFuncs executes the template with funcs which override functions of FuncMap, see: "Request funcs".
+func (tm *TemplateManager) ExecuteTemplateWithFuncs(out io.Writer, templateName string, data interface{}, funcs template.FuncMap) error {
+	return tm.executeTemplate(out, templateName, data, funcs)
+}
+
+// Adding funcs.
+//
+// AddFuncs adds functions to FuncMap after Init(eg: by a plugin), and re-parses the cached templates with the new FuncMap.
+// Names should not collide with existing functions(including "include" and builtin functions), ReplaceFuncs replaces them.
+// AddFuncsWithNamespace prefixes names with the namespace, so two plugins could not overwrite each other's helpers:
+//
+//	tplMgr.AddFuncsWithNamespace("md", template.FuncMap{"render": markdown.Render}) // {{ md_render .body }}
+//
+// The cached templates are re-parsed atomically: if any of them fails, nothing is changed.
+// Templates which are not cached(eg: failed to parse before) are parsed lazily with the new FuncMap, tenants are removed.
+
+// FuncsNamespaceSeparator separates the namespace and the name of a function. eg: "md_render"
+const FuncsNamespaceSeparator = "_"
+
+// AddFuncs adds funcs to FuncMap and re-parses the cached templates, see: "Adding funcs".
+func (tm *TemplateManager) AddFuncs(funcs template.FuncMap) error {
+	return tm.updateFuncs(funcs, false)
+}
+
+// ReplaceFuncs replaces existing functions of FuncMap and re-parses the cached templates, see: "Adding funcs".
+func (tm *TemplateManager) ReplaceFuncs(funcs template.FuncMap) error {
+	return tm.updateFuncs(funcs, true)
+}
+
+// AddFuncsWithNamespace adds funcs with names prefixed by the namespace. eg: "md" + "render" -> "md_render"
+func (tm *TemplateManager) AddFuncsWithNamespace(namespace string, funcs template.FuncMap) error {
+	if !codegenIdentRegexp.MatchString(namespace) {
+		return fmt.Errorf("invalid namespace of funcs: %q", namespace)
+	}
+	prefixed := make(template.FuncMap, len(funcs))
+	for name, fn := range funcs {
+		prefixed[namespace+FuncsNamespaceSeparator+name] = fn
+	}
+	return tm.AddFuncs(prefixed)
+}
+
+// checkFuncs returns the problems of adding funcs to funcMap, replace is true if funcs should replace existing functions.
+func checkFuncs(funcMap template.FuncMap, funcs template.FuncMap, replace bool) []string {
+	var names []string
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var errs []string
+	for _, name := range names {
+		_, exists := funcMap[name]
+		v := reflect.ValueOf(funcs[name])
+		switch {
+		case !codegenIdentRegexp.MatchString(name):
+			errs = append(errs, fmt.Sprintf("%q is not a valid function name", name))
+		case name == "include" || ContainsString(sandboxBuiltinFuncs, name):
+			errs = append(errs, fmt.Sprintf("%q is a builtin function", name))
+		case v.Kind() != reflect.Func || v.IsNil():
+			errs = append(errs, fmt.Sprintf("%q is not a function", name))
+		case !replace && exists:
+			errs = append(errs, fmt.Sprintf("%q already exists, use ReplaceFuncs to replace it", name))
+		case replace && !exists:
+			errs = append(errs, fmt.Sprintf("%q does not exist, use AddFuncs to add it", name))
+		}
+	}
+	return errs
+}
+
+// updateFuncs adds(or replaces) funcs and re-parses the cached templates atomically.
+// Nothing(FuncMap, templates, versions) is changed unless all templates are re-parsed.
+func (tm *TemplateManager) updateFuncs(funcs template.FuncMap, replace bool) error {
+	tm.funcsMutex.Lock()
+	defer tm.funcsMutex.Unlock()
+	oldFuncMap := tm.getFuncMap()
+	if errs := checkFuncs(oldFuncMap, funcs, replace); len(errs) > 0 {
+		return fmt.Errorf("could not update %d funcs: %s", len(errs), strings.Join(errs, "; "))
+	}
+
+	funcMap := make(template.FuncMap, len(oldFuncMap)+len(funcs))
+	for name, fn := range oldFuncMap {
+		funcMap[name] = fn
+	}
+	for name, fn := range funcs {
+		funcMap[name] = fn
+	}
+
+	tm.templateMutex.RLock()
+	names := tm.GetTemplateNames()
+	tm.templateMutex.RUnlock()
+	sort.Strings(names)
+	templates := make(map[string]*template.Template, len(names))
+	filesOfTemplates := make(map[string][]string, len(names))
+	var errs []string
+	for _, tplName := range names {
+		te := newTemplateEnvByParsing(tplName, tm.getModePrefixes()...)
+		tpl, err := tm.tryParseTemplateUncached(te, funcMap)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		templates[tplName] = tpl
+		filesOfTemplates[tplName] = tm.getFilesForParsing(te)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("could not re-parse %d templates with funcs: %s", len(errs), strings.Join(errs, "; "))
+	}
+
+	for tplName, files := range filesOfTemplates {
+		tm.recordTemplateFiles(tplName, files)
+	}
+	tm.setTemplatesWithFuncs(templates, funcMap)
+	tm.removeTenants()
+	if tm.DoShowDebugMessage() {
+		log.Printf("Updated funcs and re-parsed %d templates", len(templates))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package templatemanager
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var requestFuncsTemplates = map[string]string{
+	"context/layout/layout.tpl.html": `<html>{{ template "content" . }}|{{ csrfToken }}</html>`,
+	"main/form.tpl.html":             `{{ define "content" }}{{ t "hello" }} {{ .name }} {{ include "partial/hint@hint" . }}{{ end }}`,
+	"main/partial/hint.tpl.html":     `{{ define "hint" }}{{ t "hint" }}{{ end }}`,
+}
+
+// configureRequestFuncs registers the placeholders of requestFuncsTemplates.
+func configureRequestFuncs(enable bool) func(conf *TemplateConfig) {
+	return func(conf *TemplateConfig) {
+		conf.EnableRequestFuncs = enable
+		conf.FuncMap["csrfToken"] = func() string { return "" }
+		conf.FuncMap["t"] = func(s string) string { return s }
+	}
+}
+
+func TestTemplateManager_ExecuteTemplateWithFuncs(t *testing.T) {
+	for _, isDebugging := range []bool{false, true} {
+		tm := newTestManager(t, isDebugging, requestFuncsTemplates, configureRequestFuncs(true))
+		data := map[string]string{"name": "alice"}
+
+		// requests are executed concurrently with their own funcs.
+		var wg sync.WaitGroup
+		errs := make(chan string, 20)
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				out := &bytes.Buffer{}
+				token := fmt.Sprintf("token%d", i)
+				err := tm.ExecuteTemplateWithFuncs(out, "main/form.tpl.html", data, template.FuncMap{
+					"csrfToken": func() string { return token },
+					"t":         func(s string) string { return strings.ToUpper(s) },
+				})
+				if want := "<html>HELLO alice HINT|" + token + "</html>"; err != nil || out.String() != want {
+					errs <- fmt.Sprintf("debugging: %v, ExecuteTemplateWithFuncs() = %q, %v, want %q", isDebugging, out.String(), err, want)
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Error(err)
+		}
+
+		// placeholders are used without funcs
+		out := &bytes.Buffer{}
+		if err := tm.ExecuteTemplate(out, "main/form.tpl.html", data); err != nil || out.String() != "<html>hello alice hint|</html>" {
+			t.Errorf("debugging: %v, ExecuteTemplate() = %q, %v", isDebugging, out.String(), err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		enable  bool
+		funcs   template.FuncMap
+		wantErr string
+	}{
+		{"disabled", false, template.FuncMap{"csrfToken": func() string { return "x" }}, "EnableRequestFuncs is false"},
+		{"not in FuncMap", true, template.FuncMap{"currentUser": func() string { return "x" }}, `function "currentUser" is not in FuncMap`},
+		{"include", true, template.FuncMap{"include": func(string, interface{}) (template.HTML, error) { return "", nil }}, `"include" is a builtin function`},
+	}
+	for _, tt := range tests {
+		tm := newTestManager(t, false, requestFuncsTemplates, configureRequestFuncs(tt.enable))
+		err := tm.ExecuteTemplateWithFuncs(&bytes.Buffer{}, "main/form.tpl.html", nil, tt.funcs)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: ExecuteTemplateWithFuncs() error = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func BenchmarkTemplateManager_ExecuteTemplateWithFuncs(b *testing.B) {
+	tm := newTestManager(b, false, requestFuncsTemplates, configureRequestFuncs(true))
+	data := map[string]string{"name": "alice"}
+	funcs := template.FuncMap{"csrfToken": func() string { return "token" }}
+
+	b.Run("ExecuteTemplate", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := tm.ExecuteTemplate(&bytes.Buffer{}, "main/form.tpl.html", data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("ExecuteTemplateWithFuncs", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := tm.ExecuteTemplateWithFuncs(&bytes.Buffer{}, "main/form.tpl.html", data, funcs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package templatemanager
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTemplateFiles writes files(name -> source) under root.
+func writeTemplateFiles(tb testing.TB, root string, files map[string]string) {
+	for name, src := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(src), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+// newTestConfig writes files to a temp root, and returns the default config(without verbose logs) of the root.
+func newTestConfig(tb testing.TB, isDebugging bool, files map[string]string) TemplateConfig {
+	root := filepath.Join(tb.TempDir(), "templates")
+	writeTemplateFiles(tb, root, files)
+	conf := NewDefaultConfig(isDebugging)
+	conf.DirOfRoot = root
+	conf.VerboseLevel = 0
+	return conf
+}
+
+// newTestManager returns an initialized manager of newTestConfig, configure(if not nil) changes the config before New.
+func newTestManager(tb testing.TB, isDebugging bool, files map[string]string, configure func(conf *TemplateConfig)) *TemplateManager {
+	conf := newTestConfig(tb, isDebugging, files)
+	if configure != nil {
+		configure(&conf)
+	}
+	tm := New(conf)
+	if err := tm.Init(true); err != nil {
+		tb.Fatalf("Init() error = %v", err)
+	}
+	return tm
+}
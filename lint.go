@@ -0,0 +1,156 @@
+package templatemanager
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Lint checks all templates without rendering them(eg: in CI), see: "templatemanager lint" of main/.
+//
+//	error   "config"             invalid TemplateConfig, see: Validate
+//	error   "parse-error"        a main template could not be parsed(in ContextMode or FilesMode)
+//	error   "unknown-func"       a function which is not in FuncMap
+//	error   "undefined-template" a template called(from the layout) but defined nowhere
+//	error   "name-collision"     files parsed together have the same base name, the later one replaces the former
+//	warning "unused-partial"     a template defined in a context file but never called by any main template
+//	warning "unknown-extension"  a file under the main or context dir without any of Extensions(sidecar data files are skipped)
+
+const (
+	LintLevelError   = "error"
+	LintLevelWarning = "warning"
+)
+
+type LintIssue struct {
+	Level    string `json:"level"`              // "error" or "warning"
+	Kind     string `json:"kind"`               // eg: "parse-error"
+	File     string `json:"file,omitempty"`     // path relative to root
+	Template string `json:"template,omitempty"` // standard template name
+	Message  string `json:"message"`
+}
+
+func (i LintIssue) String() string {
+	s := i.Level + ": "
+	if i.File != "" {
+		s += i.File + ": "
+	}
+	return s + i.Kind + ": " + i.Message
+}
+
+var unknownFuncRegexp = regexp.MustCompile(`function "[^"]+" not defined`)
+
+// Lint parses every main template in ContextMode and FilesMode and reports all issues, sorted by file.
+func (tm *TemplateManager) Lint() []LintIssue {
+	var issues []LintIssue
+	addIssue := func(level, kind, file, tplName, format string, a ...interface{}) {
+		issues = append(issues, LintIssue{Level: level, Kind: kind, File: file, Template: tplName, Message: fmt.Sprintf(format, a...)})
+	}
+
+	if err := tm.Prepare(); err != nil {
+		if configErr, ok := err.(*ConfigError); ok {
+			for _, problem := range configErr.Problems {
+				addIssue(LintLevelError, "config", "", "", "%s", problem)
+			}
+		} else {
+			addIssue(LintLevelError, "config", "", "", "%s", err)
+		}
+		return issues
+	}
+
+	calledNames := make(map[string]bool)
+	checkedFiles := make(map[string]bool)
+	for _, f := range tm.getMainFiles() {
+		name := tm.getBasicTemplateNameByFilePath(f)
+		for _, prefix := range []TemplateModePrefix{TemplateModeContextPrefix, TemplateModeFilesPrefix} {
+			te := NewTemplateEnvByParsing(string(prefix) + name)
+			tplName := te.StandardTemplateName()
+			tpl, err := tm.tryParseTemplate(te)
+			if err != nil {
+				kind := "parse-error"
+				if unknownFuncRegexp.MatchString(err.Error()) {
+					kind = "unknown-func"
+				}
+				addIssue(LintLevelError, kind, name, tplName, "%s", err)
+				continue
+			}
+			if !te.IsContextMode() {
+				continue
+			}
+
+			called, missing := getReachableTemplateNames(tpl, tm.getEntryName(te))
+			for n := range called {
+				calledNames[n] = true
+			}
+			for _, n := range missing {
+				addIssue(LintLevelError, "undefined-template", name, tplName, "template %q is called but not defined", n)
+			}
+
+			// files of cascading context dirs replace the same base names on purpose.
+			filesOfBaseName := make(map[string][]string)
+			var baseNames []string
+			for _, filePath := range tm.getFilesForParsing(te) {
+				basicName := tm.getBasicTemplateNameByFilePath(filePath)
+				if tm.isInCascadingContextDir(basicName) {
+					continue
+				}
+				if _, ok := filesOfBaseName[path.Base(basicName)]; !ok {
+					baseNames = append(baseNames, path.Base(basicName))
+				}
+				filesOfBaseName[path.Base(basicName)] = append(filesOfBaseName[path.Base(basicName)], basicName)
+			}
+			for _, baseName := range baseNames {
+				files := filesOfBaseName[baseName]
+				key := strings.Join(files, FilesSeparator)
+				if len(files) > 1 && !checkedFiles[key] {
+					checkedFiles[key] = true
+					addIssue(LintLevelError, "name-collision", name, tplName, "files %q have the same base name %q", files, baseName)
+				}
+			}
+		}
+	}
+
+	layoutName := path.Base(tm.Config.FilePathOfLayoutRelativeToRoot)
+	for _, f := range tm.getAllContextFiles() {
+		if path.Base(f) == layoutName {
+			continue
+		}
+		for _, n := range tm.getDefinedTemplateNames(f) {
+			if !calledNames[n] {
+				addIssue(LintLevelWarning, "unused-partial", tm.getBasicTemplateNameByFilePath(f), "", "template %q is never called by any main template", n)
+			}
+		}
+	}
+
+	ignorePatterns := tm.getIgnorePatterns()
+	ignoreFileName := tm.Config.IgnoreFileName
+	if ignoreFileName == "" {
+		ignoreFileName = DefaultIgnoreFileName
+	}
+	var listed []string
+	for _, dir := range []string{tm.Config.DirOfMainRelativeToRoot, tm.Config.DirOfContextRelativeToRoot} {
+		names, _ := tm.GetLoader().List(dir)
+		for _, name := range names {
+			if !ContainsString(listed, name) {
+				listed = append(listed, name)
+			}
+		}
+	}
+	for _, name := range listed {
+		if tm.hasTemplateExtension(name) || path.Base(name) == path.Base(ignoreFileName) || ContainsString(SidecarDataExtensions, path.Ext(name)) ||
+			MatchAnyGlob(tm.Config.ExcludePatterns, name) || MatchAnyGlob(ignorePatterns, name) ||
+			(tm.Config.SkipDrafts && isDraft(name, tm.Config.CascadingContextDirName)) {
+			continue
+		}
+		addIssue(LintLevelWarning, "unknown-extension", name, "", "file is not a template, extensions are: %q", tm.getExtensions())
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Kind < issues[j].Kind
+	})
+	return issues
+}
@@ -0,0 +1,53 @@
+package templatemanager
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateManager_Lint(t *testing.T) {
+	conf := newTestConfig(t, false, map[string]string{
+		"context/layout/layout.tpl.html": `{{ template "title" . }}{{ template "content" . }}`,
+		"context/partial/ads.tpl.html":   `{{ define "ads" }}ads{{ end }}`,
+		"context/partial/nav.tpl.html":   `{{ define "nav" }}nav{{ end }}`,
+		"context/partial/readme.md":      `not a template`,
+		"main/ok.tpl.html":               `{{ define "title" }}ok{{ end }}{{ define "content" }}{{ template "ads" . }}{{ end }}`,
+		"main/broken.tpl.html":           `{{ define "title" }}broken{{ end }}{{ define "content" }}{{ if }}{{ end }}`,
+		"main/func.tpl.html":             `{{ define "title" }}func{{ end }}{{ define "content" }}{{ nofunc . }}{{ end }}`,
+		"main/missing.tpl.html":          `{{ define "title" }}missing{{ end }}`,
+		"main/dup/ads.tpl.html":          `{{ define "title" }}ads{{ end }}{{ define "content" }}ads{{ end }}`,
+		"main/notes.txt":                 `not a template`,
+	})
+	conf.Extension = ".tpl.html"
+	got := New(conf).Lint()
+
+	want := []struct {
+		level, kind, file string
+	}{
+		{LintLevelWarning, "unused-partial", "context/partial/nav.tpl.html"},
+		{LintLevelWarning, "unknown-extension", "context/partial/readme.md"},
+		{LintLevelError, "parse-error", "main/broken.tpl.html"},
+		{LintLevelError, "parse-error", "main/broken.tpl.html"},
+		{LintLevelError, "name-collision", "main/dup/ads.tpl.html"},
+		{LintLevelError, "unknown-func", "main/func.tpl.html"},
+		{LintLevelError, "unknown-func", "main/func.tpl.html"},
+		{LintLevelError, "undefined-template", "main/missing.tpl.html"},
+		{LintLevelWarning, "unknown-extension", "main/notes.txt"},
+	}
+	if len(got) != len(want) {
+		for _, issue := range got {
+			t.Log(issue)
+		}
+		t.Fatalf("Lint() got %d issues, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		if got[i].Level != w.level || got[i].Kind != w.kind || got[i].File != w.file {
+			t.Errorf("Lint()[%d] = %s, want %s: %s: %s", i, got[i], w.level, w.file, w.kind)
+		}
+	}
+
+	conf.DirOfRoot = filepath.Join(conf.DirOfRoot, "none")
+	if got := New(conf).Lint(); len(got) == 0 || got[0].Kind != "config" {
+		t.Errorf("Lint() = %v, want config errors", got)
+	}
+}
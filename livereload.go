@@ -0,0 +1,117 @@
+package templatemanager
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Live reload(debug mode only).
+//
+// If IsDebugging and LiveReload are true, a script is injected before "</body>" of rendered pages.
+// It listens to the server-sent events of LiveReloadHandler(mounted at LiveReloadPath),
+// and reloads the page when any template under DirOfRoot changes.
+//
+//	tplConfig.LiveReload = true
+//	http.Handle(templatemanager.DefaultLiveReloadPath, tplMgr.LiveReloadHandler())
+//
+// Nothing is injected or served if IsDebugging is false.
+
+const DefaultLiveReloadPath = "/_templatemanager/livereload"
+
+// LiveReloadInterval is the interval of checking changes of templates.
+var LiveReloadInterval = 500 * time.Millisecond
+
+func (tm *TemplateManager) isLiveReloading() bool {
+	return tm.Config.IsDebugging && tm.Config.LiveReload
+}
+
+func (tm *TemplateManager) getLiveReloadPath() string {
+	if tm.Config.LiveReloadPath == "" {
+		return DefaultLiveReloadPath
+	}
+	return tm.Config.LiveReloadPath
+}
+
+func (tm *TemplateManager) getLiveReloadScript() string {
+	return fmt.Sprintf(`<script>(function(){var s=new EventSource(%s);s.addEventListener("reload",function(){s.close();location.reload();});})();</script>`,
+		strconv.Quote(tm.getLiveReloadPath()))
+}
+
+// executeWithLiveReload executes and injects the live reload script before "</body>"(if any),
+// then minifies the output if EnableMinifyHtml is true. execute should not minify.
+func (tm *TemplateManager) executeWithLiveReload(execute func(out io.Writer) error, out io.Writer) error {
+	buf := &bytes.Buffer{}
+	if err := execute(buf); err != nil {
+		return err
+	}
+	b := buf.Bytes()
+	if i := bytes.LastIndex(bytes.ToLower(b), []byte("</body>")); i >= 0 {
+		b = append(append(append([]byte{}, b[:i]...), tm.getLiveReloadScript()...), b[i:]...)
+	}
+	if tm.Config.EnableMinifyHtml {
+		return minifyHtml(out, bytes.NewReader(b))
+	}
+	_, err := out.Write(b)
+	return err
+}
+
+// getLiveReloadVersion returns the version of all templates, it changes whenever any template changes.
+func (tm *TemplateManager) getLiveReloadVersion() string {
+	loader := tm.GetLoader()
+	names, _ := loader.List("")
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		version, _ := loader.Stat(name)
+		fmt.Fprintf(h, "%s\n%s\n", name, version)
+	}
+	for _, f := range tm.getStringTemplateFilePaths("") {
+		src, _ := tm.getStringTemplate(f)
+		fmt.Fprintf(h, "%s\n%s\n", f, src)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LiveReloadHandler returns the handler of server-sent events: a "reload" event is sent whenever any template changes.
+// It responds 404 if live reload is disabled(eg: not debugging).
+func (tm *TemplateManager) LiveReloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !tm.isLiveReloading() {
+			http.NotFound(w, r)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		fmt.Fprintf(w, "retry: 1000\n\n")
+		flusher.Flush()
+
+		version := tm.getLiveReloadVersion()
+		ticker := time.NewTicker(LiveReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+				if v := tm.getLiveReloadVersion(); v != version {
+					version = v
+					fmt.Fprintf(w, "event: reload\ndata: %s\n\n", v)
+					flusher.Flush()
+				}
+			}
+		}
+	})
+}
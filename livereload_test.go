@@ -0,0 +1,104 @@
+package templatemanager
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateManager_LiveReloadScript(t *testing.T) {
+	tests := []struct {
+		isDebugging bool
+		liveReload  bool
+		minify      bool
+		name        string
+		want        string
+	}{
+		{true, true, false, "C->main/index.tpl.html", `<html><body>hi<script>(function(){var s=new EventSource("/_templatemanager/livereload");`},
+		{true, true, false, "F->main/part.tpl.html", "part"},
+		{true, true, true, "C->main/index.tpl.html", `hi<script>(function(){var s=new EventSource("/_templatemanager/livereload");`},
+		{true, false, false, "C->main/index.tpl.html", "<html><body>hi</BODY></html>"},
+		{false, true, false, "C->main/index.tpl.html", "<html><body>hi</BODY></html>"},
+	}
+	for _, tt := range tests {
+		tm := newTestManager(t, tt.isDebugging, map[string]string{
+			"context/layout/layout.tpl.html": `<html><body>{{ template "content" . }}</BODY></html>`,
+			"main/index.tpl.html":            `{{ define "content" }}hi{{ end }}`,
+			"main/part.tpl.html":             `part`,
+		}, func(conf *TemplateConfig) {
+			conf.LiveReload = tt.liveReload
+			conf.EnableMinifyHtml = tt.minify
+		})
+		buf := &bytes.Buffer{}
+		if err := tm.ExecuteTemplate(buf, tt.name, nil); err != nil {
+			t.Fatal(err)
+		}
+		got := buf.String()
+		// the minifier might drop "<html>", "<body>" and end tags.
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("debugging: %v, live reload: %v, ExecuteTemplate(%q) = %q, want %q", tt.isDebugging, tt.liveReload, tt.name, got, tt.want)
+		}
+		if strings.Contains(got, "<script>") != (tt.isDebugging && tt.liveReload && tt.want != "part") {
+			t.Errorf("debugging: %v, live reload: %v, ExecuteTemplate(%q) = %q, unexpected script", tt.isDebugging, tt.liveReload, tt.name, got)
+		}
+	}
+}
+
+func TestTemplateManager_LiveReloadHandler(t *testing.T) {
+	LiveReloadInterval = 10 * time.Millisecond
+	defer func() { LiveReloadInterval = 500 * time.Millisecond }()
+
+	conf := newTestConfig(t, false, map[string]string{
+		"context/layout/layout.tpl.html": `<html><body>{{ template "content" . }}</body></html>`,
+		"main/index.tpl.html":            `{{ define "content" }}hi{{ end }}`,
+	})
+	conf.LiveReload = true
+
+	// not served in production
+	w := httptest.NewRecorder()
+	New(conf).LiveReloadHandler().ServeHTTP(w, httptest.NewRequest("GET", DefaultLiveReloadPath, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("LiveReloadHandler in production = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	conf.IsDebugging = true
+	server := httptest.NewServer(New(conf).LiveReloadHandler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want %q", got, "text/event-stream")
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	writeTemplateFiles(t, conf.DirOfRoot, map[string]string{"main/index.tpl.html": `{{ define "content" }}hello{{ end }}`})
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream closed before the reload event")
+			}
+			if line == "event: reload" {
+				return
+			}
+		case <-timeout:
+			t.Fatal("no reload event after changing a template")
+		}
+	}
+}
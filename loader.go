@@ -0,0 +1,429 @@
+package templatemanager
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Loader lists and reads templates. Names are slash separated paths relative to the template root.
+// The default loader is a FileLoader of DirOfRoot.
+type Loader interface {
+	// List returns the names of all templates under dir(relative to root, recursively).
+	List(dir string) ([]string, error)
+	// Open opens the template of name.
+	Open(name string) (io.ReadCloser, error)
+	// Stat returns the version of the template, which changes whenever the template changes.
+	Stat(name string) (version string, err error)
+}
+
+// Originer is implemented by loaders which could tell where a template comes from. eg: the root dir of a file.
+type Originer interface {
+	Origin(name string) (string, error)
+}
+
+// cleanLoaderName cleans the name(relative to root), names outside the root are rejected. eg: "main/../../secret"
+func cleanLoaderName(name string) (string, error) {
+	clean := path.Clean(strings.TrimLeft(name, "/"))
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("template %q is outside the root", name)
+	}
+	return clean, nil
+}
+
+// ------------------------------
+// -------- file loader --------
+
+// FileLoader loads templates from files under Root, names outside Root are rejected.
+type FileLoader struct {
+	Root string
+}
+
+func NewFileLoader(root string) *FileLoader {
+	return &FileLoader{Root: root}
+}
+
+// getFilePath returns the file path of name, names outside Root are rejected.
+func (l *FileLoader) getFilePath(name string) (string, error) {
+	clean, err := cleanLoaderName(name)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(l.Root, clean), nil
+}
+
+func (l *FileLoader) List(dir string) ([]string, error) {
+	var names []string
+	root, err := l.getFilePath(dir)
+	if err != nil {
+		return nil, err
+	}
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			rel, err := filepath.Rel(l.Root, p)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error happens while walking dir: %q. err: %s", root, err)
+	}
+	return names, nil
+}
+
+func (l *FileLoader) Open(name string) (io.ReadCloser, error) {
+	p, err := l.getFilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// Stat returns modification time and size of the file as the version.
+func (l *FileLoader) Stat(name string) (string, error) {
+	p, err := l.getFilePath(name)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("template %q is a directory", name)
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+}
+
+func (l *FileLoader) Origin(name string) (string, error) {
+	if _, err := l.Stat(name); err != nil {
+		return "", err
+	}
+	return l.Root, nil
+}
+
+// ------------------------------
+// -------- layered loader --------
+
+// LayeredLoader stacks loaders, a template in an earlier loader overrides the same name in later loaders.
+//
+//	eg: NewLayeredLoader(NewFileLoader("tenants/acme"), NewFileLoader("themes/dark"), NewFileLoader("templates"))
+type LayeredLoader struct {
+	Loaders []Loader
+}
+
+func NewLayeredLoader(loaders ...Loader) *LayeredLoader {
+	return &LayeredLoader{Loaders: loaders}
+}
+
+// List returns the union of names of all layers. A missing dir in a layer is skipped.
+func (l *LayeredLoader) List(dir string) ([]string, error) {
+	var names []string
+	var errs []string
+	for _, loader := range l.Loaders {
+		layerNames, err := loader.List(dir)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for _, name := range layerNames {
+			if !ContainsString(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	if len(errs) == len(l.Loaders) {
+		return nil, fmt.Errorf("could not list dir %q in any layer: %s", dir, strings.Join(errs, "; "))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// find returns the index of the first layer which has the template.
+func (l *LayeredLoader) find(name string) (int, string, error) {
+	for i, loader := range l.Loaders {
+		if version, err := loader.Stat(name); err == nil {
+			return i, version, nil
+		}
+	}
+	return -1, "", fmt.Errorf("template %q does not exist in any layer", name)
+}
+
+func (l *LayeredLoader) Open(name string) (io.ReadCloser, error) {
+	i, _, err := l.find(name)
+	if err != nil {
+		return nil, err
+	}
+	return l.Loaders[i].Open(name)
+}
+
+// Stat returns "layer-index:version", so adding or removing an override changes the version.
+func (l *LayeredLoader) Stat(name string) (string, error) {
+	i, version, err := l.find(name)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d:%s", i, version), nil
+}
+
+func (l *LayeredLoader) Origin(name string) (string, error) {
+	i, _, err := l.find(name)
+	if err != nil {
+		return "", err
+	}
+	if originer, ok := l.Loaders[i].(Originer); ok {
+		return originer.Origin(name)
+	}
+	return fmt.Sprintf("layer %d(%T)", i, l.Loaders[i]), nil
+}
+
+// ------------------------------
+// -------- memory loader --------
+
+type memoryTemplate struct {
+	src     string
+	version int
+}
+
+// MemoryLoader keeps templates in memory.
+type MemoryLoader struct {
+	templates map[string]memoryTemplate
+	version   int
+	mutex     sync.RWMutex
+}
+
+func NewMemoryLoader(templates map[string]string) *MemoryLoader {
+	l := &MemoryLoader{templates: make(map[string]memoryTemplate)}
+	for name, src := range templates {
+		l.Set(name, src)
+	}
+	return l
+}
+
+// Set adds or replaces the template of name.
+func (l *MemoryLoader) Set(name string, src string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.version += 1
+	l.templates[strings.TrimPrefix(path.Clean(name), "/")] = memoryTemplate{src: src, version: l.version}
+}
+
+func (l *MemoryLoader) Delete(name string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.templates, strings.TrimPrefix(path.Clean(name), "/"))
+}
+
+func (l *MemoryLoader) List(dir string) ([]string, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	prefix := strings.TrimPrefix(path.Clean(dir)+"/", "/")
+	if prefix == "./" {
+		prefix = ""
+	}
+	var names []string
+	for name := range l.templates {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (l *MemoryLoader) Open(name string) (io.ReadCloser, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	t, ok := l.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("template %q does not exist", name)
+	}
+	return ioutil.NopCloser(bytes.NewReader([]byte(t.src))), nil
+}
+
+func (l *MemoryLoader) Origin(name string) (string, error) {
+	if _, err := l.Stat(name); err != nil {
+		return "", err
+	}
+	return "memory", nil
+}
+
+func (l *MemoryLoader) Stat(name string) (string, error) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	t, ok := l.templates[name]
+	if !ok {
+		return "", fmt.Errorf("template %q does not exist", name)
+	}
+	return strconv.Itoa(t.version), nil
+}
+
+// ------------------------------
+// -------- reloading --------
+
+// SetLoader sets the loader of templates, it should be called before Init.
+func (tm *TemplateManager) SetLoader(loader Loader) {
+	tm.versionMutex.Lock()
+	tm.loader = loader
+	tm.versionMutex.Unlock()
+	tm.removeTenants()
+}
+
+// GetLoader returns the loader. Default is a FileLoader of DirOfRoot,
+// or a LayeredLoader of DirsOfOverrideRoot and DirOfRoot if DirsOfOverrideRoot is set.
+func (tm *TemplateManager) GetLoader() Loader {
+	tm.versionMutex.RLock()
+	defer tm.versionMutex.RUnlock()
+	if tm.loader != nil {
+		return tm.loader
+	}
+	if len(tm.Config.DirsOfOverrideRoot) > 0 {
+		var loaders []Loader
+		for _, dir := range tm.Config.DirsOfOverrideRoot {
+			loaders = append(loaders, NewFileLoader(dir))
+		}
+		return NewLayeredLoader(append(loaders, NewFileLoader(tm.Config.DirOfRoot))...)
+	}
+	return NewFileLoader(tm.Config.DirOfRoot)
+}
+
+// GetOrigin returns where the template file comes from(eg: the root dir), "" if the loader can not tell.
+func (tm *TemplateManager) GetOrigin(filePath string) string {
+	if _, ok := tm.getStringTemplate(filePath); ok {
+		return "string"
+	}
+	if originer, ok := tm.GetLoader().(Originer); ok {
+		if origin, err := originer.Origin(tm.getBasicTemplateNameByFilePath(filePath)); err == nil {
+			return origin
+		}
+	}
+	return ""
+}
+
+// recordVersions records versions of the template files(string templates are skipped).
+func (tm *TemplateManager) recordVersions(tplName string, filePaths []string) {
+	loader := tm.GetLoader()
+	versions := make(map[string]string)
+	for _, f := range filePaths {
+		if _, ok := tm.getStringTemplate(f); ok {
+			continue
+		}
+		name := tm.getBasicTemplateNameByFilePath(f)
+		if version, err := loader.Stat(name); err == nil {
+			versions[name] = version
+		}
+	}
+	tm.versionMutex.Lock()
+	defer tm.versionMutex.Unlock()
+	tm.versions[tplName] = versions
+}
+
+// tryParseTemplate parses the templateEnv, a panic while parsing is returned as an error.
+func (tm *TemplateManager) tryParseTemplate(te *TemplateEnv) (tpl *template.Template, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("could not parse template: %q. err: %v", te.StandardTemplateName(), r)
+		}
+	}()
+	return tm.parseTemplate(te), nil
+}
+
+// tryParseTemplateUncached parses the templateEnv with funcMap without caching it(or recording its versions).
+func (tm *TemplateManager) tryParseTemplateUncached(te *TemplateEnv, funcMap template.FuncMap) (tpl *template.Template, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("could not parse template: %q. err: %v", te.StandardTemplateName(), r)
+		}
+	}()
+	return tm.parseTemplateFiles(te.StandardTemplateName(), tm.getFilesForParsing(te), funcMap), nil
+}
+
+// ReloadChanged re-parses the cached templates whose files changed(by comparing versions of the loader).
+// All cached templates are re-parsed if the context files are added or removed.
+// It returns the names of re-parsed templates.
+func (tm *TemplateManager) ReloadChanged() ([]string, error) {
+	loader := tm.GetLoader()
+	contextFiles := tm.getAllContextFiles()
+
+	tm.versionMutex.Lock()
+	contextChanged := strings.Join(contextFiles, FilesSeparator) != strings.Join(tm.contextFiles, FilesSeparator)
+	tm.contextFiles = contextFiles
+	var changed []string
+	for tplName, versions := range tm.versions {
+		for name, version := range versions {
+			if v, err := loader.Stat(name); contextChanged || err != nil || v != version {
+				changed = append(changed, tplName)
+				break
+			}
+		}
+	}
+	tm.versionMutex.Unlock()
+
+	tm.resolveMutex.Lock()
+	tm.nameIndex = tm.buildNameIndex()
+	tm.cascadingIndex = tm.buildCascadingIndex()
+	tm.aliases = make(map[string]string)
+	tm.resolveMutex.Unlock()
+
+	sort.Strings(changed)
+	var errs []string
+	for _, tplName := range changed {
+		if tm.DoShowDebugMessage() {
+			log.Printf("Template changed, reloading: %q", tplName)
+		}
+		if _, err := tm.tryParseTemplate(newTemplateEnvByParsing(tplName, tm.getModePrefixes()...)); err != nil {
+			// keep the old template, but don't check it again until it changes.
+			log.Printf("Failed reloading template: %q. err: %s", tplName, err)
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return changed, fmt.Errorf("failed reloading %d templates: %s", len(errs), strings.Join(errs, "; "))
+	}
+	return changed, nil
+}
+
+// StartReloading calls ReloadChanged(and ReloadTenant of every tenant) every interval until stop is called.
+func (tm *TemplateManager) StartReloading(interval time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := tm.ReloadChanged(); err != nil {
+					log.Printf("Reloading templates error: %s", err)
+				}
+				for _, tenant := range tm.GetTenants() {
+					if _, err := tm.ReloadTenant(tenant); err != nil {
+						log.Printf("Reloading templates of tenant %q error: %s", tenant, err)
+					}
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
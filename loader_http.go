@@ -0,0 +1,180 @@
+package templatemanager
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTPLoader loads templates from an http server(eg: a CMS or an asset server):
+//
+//	GET BaseURL/_index          json object of name -> version. eg: {"main/demo/demo1.tpl.html": "3"}
+//	GET BaseURL/<name>          source of the template. eg: BaseURL/main/demo/demo1.tpl.html
+//
+// The index is cached for IndexTTL, so List and Stat of many templates send one request.
+// HTTPLoaderHandler serves any Loader by this protocol.
+type HTTPLoader struct {
+	BaseURL  string        // eg: "https://cms.example.com/templates"
+	Client   *http.Client  // default: http.DefaultClient
+	IndexTTL time.Duration // 0: the index is fetched on every List and Stat
+
+	index     map[string]string
+	indexTime time.Time
+	mutex     sync.Mutex
+}
+
+// HTTPLoaderIndexName is the name of the index, see: HTTPLoader.
+const HTTPLoaderIndexName = "_index"
+
+// DefaultHTTPLoaderIndexTTL is the IndexTTL of NewHTTPLoader.
+var DefaultHTTPLoaderIndexTTL = time.Second
+
+func NewHTTPLoader(baseURL string) *HTTPLoader {
+	return &HTTPLoader{BaseURL: strings.TrimRight(baseURL, "/"), IndexTTL: DefaultHTTPLoaderIndexTTL}
+}
+
+func (l *HTTPLoader) getClient() *http.Client {
+	if l.Client != nil {
+		return l.Client
+	}
+	return http.DefaultClient
+}
+
+// getURL returns the url of name, names outside the root are rejected.
+func (l *HTTPLoader) getURL(name string) (string, error) {
+	clean, err := cleanLoaderName(name)
+	if err != nil {
+		return "", err
+	}
+	parts := strings.Split(clean, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.TrimRight(l.BaseURL, "/") + "/" + strings.Join(parts, "/"), nil
+}
+
+func (l *HTTPLoader) get(name string) (*http.Response, error) {
+	u, err := l.getURL(name)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.getClient().Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("could not get template: %q. err: %s", name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("could not get template: %q. status: %s", name, resp.Status)
+	}
+	return resp, nil
+}
+
+// getIndex returns the cached index, it is fetched again after IndexTTL.
+func (l *HTTPLoader) getIndex() (map[string]string, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.index != nil && time.Since(l.indexTime) < l.IndexTTL {
+		return l.index, nil
+	}
+	resp, err := l.get(HTTPLoaderIndexName)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	index := make(map[string]string)
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("could not decode the index of %q. err: %s", l.BaseURL, err)
+	}
+	l.index, l.indexTime = index, time.Now()
+	return index, nil
+}
+
+func (l *HTTPLoader) List(dir string) ([]string, error) {
+	index, err := l.getIndex()
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimPrefix(path.Clean(dir)+"/", "/")
+	if prefix == "./" {
+		prefix = ""
+	}
+	var names []string
+	for name := range index {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (l *HTTPLoader) Open(name string) (io.ReadCloser, error) {
+	resp, err := l.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (l *HTTPLoader) Stat(name string) (string, error) {
+	index, err := l.getIndex()
+	if err != nil {
+		return "", err
+	}
+	version, ok := index[name]
+	if !ok {
+		return "", fmt.Errorf("template %q does not exist", name)
+	}
+	return version, nil
+}
+
+func (l *HTTPLoader) Origin(name string) (string, error) {
+	if _, err := l.Stat(name); err != nil {
+		return "", err
+	}
+	return l.BaseURL, nil
+}
+
+// HTTPLoaderHandler serves templates of the loader for HTTPLoader.
+//
+//	http.Handle("/templates/", http.StripPrefix("/templates", templatemanager.HTTPLoaderHandler(loader)))
+func HTTPLoaderHandler(loader Loader) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, err := cleanLoaderName(r.URL.Path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if name == HTTPLoaderIndexName {
+			names, err := loader.List("")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			index := make(map[string]string)
+			for _, n := range names {
+				if version, err := loader.Stat(n); err == nil {
+					index[n] = version
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(index)
+			return
+		}
+		f, err := loader.Open(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.Copy(w, f)
+	})
+}
@@ -0,0 +1,65 @@
+package templatemanager
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHTTPLoader(t *testing.T) {
+	memoryLoader := NewMemoryLoader(newMemoryLoaderTemplates())
+	server := httptest.NewServer(HTTPLoaderHandler(memoryLoader))
+	defer server.Close()
+
+	loader := NewHTTPLoader(server.URL)
+	loader.IndexTTL = 0
+	names, err := loader.List("context")
+	if want := []string{"context/layout/layout.tpl.html", "context/partial/ads.tpl.html"}; err != nil || !reflect.DeepEqual(names, want) {
+		t.Errorf("List() got = %q, err = %v, want %q", names, err, want)
+	}
+	if _, err := loader.Open("main/../../secret.txt"); err == nil {
+		t.Errorf("Open() of a name outside the root expects an error")
+	}
+	if _, err := loader.Stat("main/none.tpl.html"); err == nil {
+		t.Errorf("Stat() of a missing template expects an error")
+	}
+	resp, err := http.Get(server.URL + "/main/%2E%2E/%2E%2E/secret.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET a name outside the root = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+
+	tm := NewDefault(false)
+	tm.Config.DirOfRoot = "http"
+	tm.SetLoader(loader)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := tm.ExecuteTemplate(out, "home/home", nil); err != nil || out.String() != "<html>home: ads</html>" {
+		t.Errorf("ExecuteTemplate() got = %q, err = %v", out.String(), err)
+	}
+
+	memoryLoader.Set("main/home/home.tpl.html", `{{ define "content" }}cms home{{ end }}`)
+	if changed, err := tm.ReloadChanged(); err != nil || len(changed) != 2 {
+		t.Errorf("ReloadChanged() got = %q, err = %v", changed, err)
+	}
+	out.Reset()
+	if err := tm.ExecuteTemplate(out, "home/home", nil); err != nil || out.String() != "<html>cms home</html>" {
+		t.Errorf("ExecuteTemplate() got = %q, err = %v", out.String(), err)
+	}
+	f, err := loader.Open("main/home/home.tpl.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if b, _ := ioutil.ReadAll(f); string(b) != `{{ define "content" }}cms home{{ end }}` {
+		t.Errorf("Open() got = %q", b)
+	}
+}
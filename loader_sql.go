@@ -0,0 +1,77 @@
+package templatemanager
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// SQLLoader loads templates from a sql table. The default queries fit a table like(sqlite/mysql):
+//
+//	CREATE TABLE templates (
+//		name    VARCHAR(255) PRIMARY KEY, -- path relative to root, eg: "main/demo/demo1.tpl.html"
+//		source  TEXT NOT NULL,
+//		version VARCHAR(64) NOT NULL      -- changes whenever source changes, eg: updated_at or a counter
+//	);
+//
+// Change the queries for other placeholders(eg: "$1" of postgres) or schemas.
+type SQLLoader struct {
+	DB        *sql.DB
+	ListQuery string // args: name prefix(LIKE pattern); returns: name
+	OpenQuery string // args: name; returns: source
+	StatQuery string // args: name; returns: version
+}
+
+func NewSQLLoader(db *sql.DB, table string) *SQLLoader {
+	return &SQLLoader{
+		DB:        db,
+		ListQuery: fmt.Sprintf("SELECT name FROM %s WHERE name LIKE ? ORDER BY name", table),
+		OpenQuery: fmt.Sprintf("SELECT source FROM %s WHERE name = ?", table),
+		StatQuery: fmt.Sprintf("SELECT version FROM %s WHERE name = ?", table),
+	}
+}
+
+func (l *SQLLoader) List(dir string) ([]string, error) {
+	prefix := strings.TrimPrefix(path.Clean(dir)+"/", "/")
+	if prefix == "./" {
+		prefix = ""
+	}
+	rows, err := l.DB.Query(l.ListQuery, prefix+"%")
+	if err != nil {
+		return nil, fmt.Errorf("could not list templates of dir: %q. err: %s", dir, err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		// LIKE treats "_" as a wildcard.
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names, rows.Err()
+}
+
+func (l *SQLLoader) Open(name string) (io.ReadCloser, error) {
+	var src string
+	if err := l.DB.QueryRow(l.OpenQuery, name).Scan(&src); err != nil {
+		return nil, fmt.Errorf("could not open template: %q. err: %s", name, err)
+	}
+	return ioutil.NopCloser(bytes.NewReader([]byte(src))), nil
+}
+
+func (l *SQLLoader) Stat(name string) (string, error) {
+	var version string
+	if err := l.DB.QueryRow(l.StatQuery, name).Scan(&version); err != nil {
+		return "", fmt.Errorf("could not stat template: %q. err: %s", name, err)
+	}
+	return version, nil
+}
@@ -0,0 +1,57 @@
+package templatemanager
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestSQLLoader(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE templates (name VARCHAR(255) PRIMARY KEY, source TEXT NOT NULL, version VARCHAR(64) NOT NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, src := range newMemoryLoaderTemplates() {
+		if _, err := db.Exec(`INSERT INTO templates(name, source, version) VALUES(?, ?, '1')`, name, src); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loader := NewSQLLoader(db, "templates")
+	names, err := loader.List("main")
+	if err != nil || len(names) != 1 || names[0] != "main/home/home.tpl.html" {
+		t.Errorf("List() got = %q, err = %v", names, err)
+	}
+
+	tm := NewDefault(false)
+	tm.Config.DirOfRoot = "db"
+	tm.SetLoader(loader)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := tm.ExecuteTemplate(out, "home/home", nil); err != nil || out.String() != "<html>home: ads</html>" {
+		t.Errorf("ExecuteTemplate() got = %q, err = %v", out.String(), err)
+	}
+
+	_, err = db.Exec(`UPDATE templates SET source = ?, version = '2' WHERE name = ?`, `{{ define "content" }}cms home{{ end }}`, "main/home/home.tpl.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := tm.ReloadChanged(); err != nil || len(changed) != 2 {
+		t.Errorf("ReloadChanged() got = %q, err = %v", changed, err)
+	}
+	out.Reset()
+	if err := tm.ExecuteTemplate(out, "home/home", nil); err != nil || out.String() != "<html>cms home</html>" {
+		t.Errorf("ExecuteTemplate() got = %q, err = %v", out.String(), err)
+	}
+}
@@ -0,0 +1,141 @@
+package templatemanager
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newMemoryLoaderTemplates() map[string]string {
+	return map[string]string{
+		"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`,
+		"context/partial/ads.tpl.html":   `{{ define "ads" }}ads{{ end }}`,
+		"main/home/home.tpl.html":        `{{ define "content" }}home: {{ template "ads" . }}{{ end }}`,
+	}
+}
+
+func TestFileLoader_OutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplateFiles(t, dir, map[string]string{
+		"secret.txt":                "secret",
+		"templates/main/a.tpl.html": "a",
+	})
+	loader := NewFileLoader(filepath.Join(dir, "templates"))
+	for _, name := range []string{"../secret.txt", "main/../../secret.txt", "/../secret.txt"} {
+		if _, err := loader.Open(name); err == nil {
+			t.Errorf("Open(%q) expects an error", name)
+		}
+		if _, err := loader.Stat(name); err == nil {
+			t.Errorf("Stat(%q) expects an error", name)
+		}
+	}
+	if _, err := loader.List(".."); err == nil {
+		t.Errorf("List(%q) expects an error", "..")
+	}
+	if _, err := loader.Stat("/main/./a.tpl.html"); err != nil {
+		t.Errorf("Stat() error = %v", err)
+	}
+}
+
+func TestTemplateManager_MemoryLoader(t *testing.T) {
+	loader := NewMemoryLoader(newMemoryLoaderTemplates())
+	tm := NewDefault(false)
+	tm.Config.DirOfRoot = "memory"
+	tm.SetLoader(loader)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(name string) string {
+		out := &bytes.Buffer{}
+		if err := tm.ExecuteTemplate(out, name, nil); err != nil {
+			t.Fatalf("ExecuteTemplate() error = %v", err)
+		}
+		return out.String()
+	}
+	if got := render("home/home"); got != "<html>home: ads</html>" {
+		t.Errorf("ExecuteTemplate() got = %q", got)
+	}
+
+	// nothing changed
+	if changed, err := tm.ReloadChanged(); err != nil || len(changed) != 0 {
+		t.Errorf("ReloadChanged() got = %q, err = %v", changed, err)
+	}
+
+	loader.Set("context/partial/ads.tpl.html", `{{ define "ads" }}new ads{{ end }}`)
+	changed, err := tm.ReloadChanged()
+	if err != nil || !ContainsString(changed, "C->main/home/home.tpl.html") || ContainsString(changed, "F->main/home/home.tpl.html") {
+		t.Errorf("ReloadChanged() got = %q, err = %v", changed, err)
+	}
+	if got := render("home/home"); got != "<html>home: new ads</html>" {
+		t.Errorf("ExecuteTemplate() got = %q", got)
+	}
+
+	// a broken template keeps the old one.
+	loader.Set("main/home/home.tpl.html", `{{ define "content" }}`)
+	if _, err := tm.ReloadChanged(); err == nil {
+		t.Errorf("ReloadChanged() expects an error")
+	}
+	if got := render("home/home"); got != "<html>home: new ads</html>" {
+		t.Errorf("ExecuteTemplate() got = %q", got)
+	}
+
+	// a new template
+	loader.Set("main/about.tpl.html", `{{ define "content" }}about{{ end }}`)
+	if _, err := tm.ReloadChanged(); err != nil {
+		t.Errorf("ReloadChanged() error = %v", err)
+	}
+	if got := render("about"); !strings.Contains(got, "about") {
+		t.Errorf("ExecuteTemplate() got = %q", got)
+	}
+}
+
+func TestTemplateManager_DirsOfOverrideRoot(t *testing.T) {
+	dir := t.TempDir()
+	base, theme, tenant := filepath.Join(dir, "templates"), filepath.Join(dir, "theme"), filepath.Join(dir, "tenant")
+	writeTemplateFiles(t, base, map[string]string{
+		"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`,
+		"context/partial/ads.tpl.html":   `{{ define "ads" }}ads{{ end }}`,
+		"main/home.tpl.html":             `{{ define "content" }}home {{ template "ads" . }}{{ end }}`,
+		"main/about.tpl.html":            `{{ define "content" }}about {{ template "ads" . }}{{ end }}`,
+	})
+	writeTemplateFiles(t, theme, map[string]string{
+		"context/layout/layout.tpl.html": `<html class="dark">{{ template "content" . }}</html>`,
+		"context/partial/ads.tpl.html":   `{{ define "ads" }}dark ads{{ end }}`,
+	})
+	writeTemplateFiles(t, tenant, map[string]string{
+		"main/home.tpl.html": `{{ define "content" }}acme home {{ template "ads" . }}{{ end }}`,
+	})
+
+	conf := NewDefaultConfig(false)
+	conf.DirOfRoot = base
+	conf.DirsOfOverrideRoot = []string{tenant, theme}
+	tm := New(conf)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"home":  `<html class="dark">acme home dark ads</html>`,
+		"about": `<html class="dark">about dark ads</html>`,
+	}
+	for name, want := range tests {
+		out := &bytes.Buffer{}
+		if err := tm.ExecuteTemplate(out, name, nil); err != nil || out.String() != want {
+			t.Errorf("ExecuteTemplate(%q) got = %q, err = %v, want = %q", name, out.String(), err, want)
+		}
+	}
+
+	report := tm.Report()
+	for _, want := range []string{
+		fmt.Sprintf("%q -> %q", filepath.Join(base, "main/home.tpl.html"), tenant),
+		fmt.Sprintf("%q -> %q", filepath.Join(base, "main/about.tpl.html"), base),
+		fmt.Sprintf("%q -> %q", filepath.Join(base, "context/partial/ads.tpl.html"), theme),
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Report() does not contain: %s", want)
+		}
+	}
+}
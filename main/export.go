@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/darkdarkfruit/templatemanager"
+)
+
+// stubUnknownFuncs stubs functions of templates which are not in FuncMap, it returns the stubbed names.
+func stubUnknownFuncs(conf templatemanager.TemplateConfig) []string {
+	var stubbed []string
+	for {
+		found := false
+		for _, issue := range templatemanager.New(conf).Lint() {
+			m := unknownFuncRegexp.FindStringSubmatch(issue.Message)
+			if issue.Kind == "unknown-func" && m != nil && conf.FuncMap[m[1]] == nil {
+				conf.FuncMap[m[1]] = stubFunc
+				stubbed = append(stubbed, m[1])
+				found = true
+			}
+		}
+		if !found {
+			return stubbed
+		}
+	}
+}
+
+// runExport runs "templatemanager export", it returns the exit code: 0: ok, 1: export error, 2: bad usage or config.
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	configFile := flags.String("config", "", "config file(.yaml, .yml, .toml or .json), default: NewDefaultConfig")
+	root := flags.String("root", "", "template root dir, overrides dir_of_root of the config")
+	outDir := flags.String("out", "", "output dir")
+	only := flags.String("only", "", "glob patterns of main templates to export, separated by \",\". eg: \"main/blog/**\"")
+	workers := flags.Int("workers", 0, "number of pages rendered in parallel, 0: number of CPUs")
+	prettyURLs := flags.Bool("pretty", false, "pretty urls: demo/demo1/index.html instead of demo/demo1.html")
+	incremental := flags.Bool("incremental", false, "only render pages changed since the last export")
+	manifest := flags.String("manifest", "", "file recording hashes of exported pages, default: <out>.templatemanager-export.json")
+	stubFuncs := flags.String("stub-funcs", "", "functions to stub, separated by \",\". eg: \"FormatAsDate,unescaped\"")
+	stubUnknown := flags.Bool("stub-unknown", true, "stub every function which is not defined")
+	verbose := flags.Bool("v", false, "show logs of the template manager")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *outDir == "" {
+		fmt.Fprintf(os.Stderr, "-out is required\n")
+		flags.Usage()
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	conf, err := loadConfig(*configFile, *root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	conf.VerboseLevel = 0
+	for _, f := range strings.Split(*stubFuncs, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			conf.FuncMap[f] = stubFunc
+		}
+	}
+	if *stubUnknown {
+		for _, f := range stubUnknownFuncs(conf) {
+			fmt.Fprintf(os.Stderr, "stubbed function: %q\n", f)
+		}
+	}
+
+	tplMgr := templatemanager.New(conf)
+	if err := tplMgr.Prepare(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	options := templatemanager.ExportOptions{Workers: *workers, PrettyURLs: *prettyURLs, Incremental: *incremental, ManifestPath: *manifest}
+	for _, pattern := range strings.Split(*only, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			options.Patterns = append(options.Patterns, pattern)
+		}
+	}
+	result, err := tplMgr.Export(*outDir, nil, options)
+	for _, f := range result.Rendered {
+		fmt.Printf("rendered: %s\n", f)
+	}
+	fmt.Printf("%d rendered, %d skipped\n", len(result.Rendered), len(result.Skipped))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
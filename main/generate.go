@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/darkdarkfruit/templatemanager"
+)
+
+// runGenerate runs "templatemanager generate", it returns the exit code: 0: ok, 1: generate error, 2: bad usage or config.
+//
+//	//go:generate go run github.com/darkdarkfruit/templatemanager/main generate -root templates -package views -out templates_gen.go -render
+func runGenerate(args []string) int {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	configFile := flags.String("config", "", "config file(.yaml, .yml, .toml or .json), default: NewDefaultConfig")
+	root := flags.String("root", "", "template root dir, overrides dir_of_root of the config")
+	out := flags.String("out", "", "output go file, default: stdout")
+	pkg := flags.String("package", "templates", "package name of the generated code")
+	renderFuncs := flags.Bool("render", false, "generate typed render functions of templates with @data comments")
+	verbose := flags.Bool("v", false, "show logs of the template manager")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	conf, err := loadConfig(*configFile, *root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	conf.VerboseLevel = 0
+	// functions are not called while generating.
+	stubUnknownFuncs(conf)
+
+	tplMgr := templatemanager.New(conf)
+	if err := tplMgr.Prepare(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	code, err := tplMgr.GenerateCode(templatemanager.GenerateOptions{Package: *pkg, RenderFuncs: *renderFuncs})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	if *out == "" {
+		os.Stdout.Write(code)
+		return 0
+	}
+	if err := ioutil.WriteFile(*out, code, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
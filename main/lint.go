@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/darkdarkfruit/templatemanager"
+)
+
+// runLint runs "templatemanager lint", it returns the exit code: 0: ok, 1: lint errors, 2: bad usage or config.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	configFile := flags.String("config", "", "config file(.yaml, .yml, .toml or .json), default: NewDefaultConfig")
+	root := flags.String("root", "", "template root dir, overrides dir_of_root of the config")
+	format := flags.String("format", "text", "output format: text or json")
+	strict := flags.Bool("strict", false, "exit 1 on warnings too")
+	stubFuncs := flags.String("stub-funcs", "", "functions defined in go code, separated by \",\". eg: \"FormatAsDate,unescaped\"")
+	stubUnknown := flags.Bool("stub-unknown", false, "stub every function which is not defined, \"unknown-func\" errors are not reported")
+	verbose := flags.Bool("v", false, "show logs of the template manager")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "text" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format: %q\n", *format)
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	conf, err := loadConfig(*configFile, *root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	conf.VerboseLevel = 0
+	for _, f := range strings.Split(*stubFuncs, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			conf.FuncMap[f] = stubFunc
+		}
+	}
+	if *stubUnknown {
+		for _, f := range stubUnknownFuncs(conf) {
+			fmt.Fprintf(os.Stderr, "stubbed function: %q\n", f)
+		}
+	}
+	issues := templatemanager.New(conf).Lint()
+
+	errors, warnings := 0, 0
+	for _, issue := range issues {
+		if issue.Level == templatemanager.LintLevelError {
+			errors += 1
+		} else {
+			warnings += 1
+		}
+	}
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if issues == nil {
+			issues = []templatemanager.LintIssue{}
+		}
+		encoder.Encode(map[string]interface{}{"issues": issues, "errors": errors, "warnings": warnings})
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		fmt.Printf("%d errors, %d warnings\n", errors, warnings)
+	}
+
+	if errors > 0 || (*strict && warnings > 0) {
+		return 1
+	}
+	return 0
+}
@@ -3,11 +3,26 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"fmt"
 	"github.com/darkdarkfruit/templatemanager"
 	"log"
+	"os"
 	"time"
 )
 
+const usage = `usage: templatemanager [command] [flags]
+
+commands:
+	lint	check all templates, exit 1 if there are errors
+	render	render a template with data of a json/yaml file
+	export	render all main templates to a dir(static site)
+	preview	serve a preview of all templates with sidecar data
+	generate	generate go constants(and typed render functions) of main templates
+	demo	render the demo templates(default)
+
+Run "templatemanager <command> -h" for flags of the command.
+`
+
 var cnt = 0
 
 func executeTemplate(tplMgr *templatemanager.TemplateManager, tplName string, data map[string]interface{}) *templatemanager.TemplateManager {
@@ -25,6 +40,44 @@ func executeTemplate(tplMgr *templatemanager.TemplateManager, tplName string, da
 
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	command := "demo"
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+	switch command {
+	case "lint":
+		os.Exit(runLint(os.Args[2:]))
+	case "render":
+		os.Exit(runRender(os.Args[2:]))
+	case "export":
+		os.Exit(runExport(os.Args[2:]))
+	case "preview":
+		os.Exit(runPreview(os.Args[2:]))
+	case "generate":
+		os.Exit(runGenerate(os.Args[2:]))
+	case "demo":
+		runDemo()
+	case "-h", "-help", "--help", "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command: %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+// loadConfig loads the config file(and TEMPLATEMANAGER_* environment variables), root overrides DirOfRoot if set.
+func loadConfig(configFile string, root string) (templatemanager.TemplateConfig, error) {
+	conf, err := templatemanager.LoadConfig(configFile)
+	if err != nil {
+		return conf, err
+	}
+	if root != "" {
+		conf.DirOfRoot = root
+	}
+	return conf, nil
+}
+
+func runDemo() {
 	//tplConf := DefaultConfig(true)
 	//tplMgr := New(tplConf)
 	tplMgr := templatemanager.NewDefault(true)
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/darkdarkfruit/templatemanager"
+)
+
+// runPreview runs "templatemanager preview", it returns the exit code: 1: server error, 2: bad usage or config.
+func runPreview(args []string) int {
+	flags := flag.NewFlagSet("preview", flag.ContinueOnError)
+	configFile := flags.String("config", "", "config file(.yaml, .yml, .toml or .json), default: NewDefaultConfig")
+	root := flags.String("root", "", "template root dir, overrides dir_of_root of the config")
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	stubFuncs := flags.String("stub-funcs", "", "functions to stub, separated by \",\". eg: \"FormatAsDate,unescaped\"")
+	stubUnknown := flags.Bool("stub-unknown", true, "stub every function which is not defined")
+	verbose := flags.Bool("v", false, "show logs of the template manager")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	conf, err := loadConfig(*configFile, *root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	conf.VerboseLevel = 0
+	for _, f := range strings.Split(*stubFuncs, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			conf.FuncMap[f] = stubFunc
+		}
+	}
+	if *stubUnknown {
+		for _, f := range stubUnknownFuncs(conf) {
+			fmt.Fprintf(os.Stderr, "stubbed function: %q\n", f)
+		}
+	}
+
+	tplMgr := templatemanager.New(conf)
+	if err := tplMgr.Prepare(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	fmt.Printf("Previewing templates of %q at http://%s/\n", conf.DirOfRoot, *addr)
+	if err := http.ListenAndServe(*addr, tplMgr.PreviewHandler()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
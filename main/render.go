@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/darkdarkfruit/templatemanager"
+	"gopkg.in/yaml.v3"
+)
+
+const renderUsage = `usage: templatemanager render <name> [flags]
+
+name is a template name of any mode. eg: "demo/demo1", "F->main/demo/demo1.tpl.html", "demo/demo1?layout=context/layout/simple.tpl.html"
+`
+
+var unknownFuncRegexp = regexp.MustCompile(`function "([^"]+)" not defined`)
+
+// stubFunc replaces functions which only exist in go code, it prints its arguments.
+func stubFunc(args ...interface{}) string {
+	return fmt.Sprint(args...)
+}
+
+// readData reads data of the json or yaml(by extension) file, "-" reads json from stdin.
+func readData(filePath string) (interface{}, error) {
+	if filePath == "" {
+		return nil, nil
+	}
+	var b []byte
+	var err error
+	if filePath == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(filePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &data)
+	default:
+		err = json.Unmarshal(b, &data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse data file: %q. err: %s", filePath, err)
+	}
+	return data, nil
+}
+
+// tryExecuteTemplate executes the template, a panic while parsing is returned as an error.
+func tryExecuteTemplate(tplMgr *templatemanager.TemplateManager, out io.Writer, name string, data interface{}) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return tplMgr.ExecuteTemplate(out, name, data)
+}
+
+// runRender runs "templatemanager render", it returns the exit code: 0: ok, 1: render error, 2: bad usage or config.
+func runRender(args []string) int {
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), renderUsage)
+		flags.PrintDefaults()
+	}
+	configFile := flags.String("config", "", "config file(.yaml, .yml, .toml or .json), default: NewDefaultConfig")
+	root := flags.String("root", "", "template root dir, overrides dir_of_root of the config")
+	dataFile := flags.String("data", "", "data file(.json, .yaml or .yml), \"-\": json from stdin")
+	outFile := flags.String("out", "", "output file, default: stdout")
+	minifyHtml := flags.Bool("minify-html", false, "minify the output html")
+	minifyTemplate := flags.Bool("minify-template", false, "minify templates before parsing")
+	stubFuncs := flags.String("stub-funcs", "", "functions to stub, separated by \",\". eg: \"FormatAsDate,unescaped\"")
+	stubUnknown := flags.Bool("stub-unknown", true, "stub every function which is not defined")
+	verbose := flags.Bool("v", false, "show logs of the template manager")
+
+	// the name could be put before the flags.
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	// flags could also follow the name.
+	if name == "" && flags.NArg() > 0 {
+		name = flags.Arg(0)
+		if err := flags.Parse(flags.Args()[1:]); err != nil {
+			return 2
+		}
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unexpected arguments: %q\n", flags.Args())
+		flags.Usage()
+		return 2
+	}
+	if name == "" {
+		flags.Usage()
+		return 2
+	}
+	if !*verbose {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	conf, err := loadConfig(*configFile, *root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	conf.VerboseLevel = 0
+	conf.IsDebugging = true // templates are parsed on every try
+	conf.EnableMinifyHtml = conf.EnableMinifyHtml || *minifyHtml
+	conf.EnableMinifyTemplate = conf.EnableMinifyTemplate || *minifyTemplate
+	for _, f := range strings.Split(*stubFuncs, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			conf.FuncMap[f] = stubFunc
+		}
+	}
+	data, err := readData(*dataFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+
+	tplMgr := templatemanager.New(conf)
+	if err := tplMgr.Prepare(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 2
+	}
+	buf := &bytes.Buffer{}
+	for {
+		buf.Reset()
+		err = tryExecuteTemplate(tplMgr, buf, name, data)
+		m := unknownFuncRegexp.FindStringSubmatch(fmt.Sprint(err))
+		if err == nil || !*stubUnknown || m == nil || tplMgr.Config.FuncMap[m[1]] != nil {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "stubbed function: %q\n", m[1])
+		tplMgr.Config.FuncMap[m[1]] = stubFunc
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not render template: %q. err: %s\n", name, err)
+		return 1
+	}
+
+	if *outFile == "" {
+		_, err = buf.WriteTo(os.Stdout)
+	} else {
+		err = ioutil.WriteFile(*outFile, buf.Bytes(), 0644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package templatemanager
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Mode decides how a templateEnv is parsed and executed.
+// ContextMode("C->"), FilesMode("F->") and StringMode("S->") are registered by default, others could be registered by RegisterMode.
+type Mode interface {
+	// Prefix is the prefix of template names in this mode. eg: "C->"
+	Prefix() TemplateModePrefix
+	// FilesForParsing returns the files to parse for the templateEnv.
+	FilesForParsing(tm *TemplateManager, te *TemplateEnv) []string
+	// EntryName returns the name of the template to execute.
+	EntryName(tm *TemplateManager, te *TemplateEnv) string
+}
+
+type contextMode struct{}
+
+func (contextMode) Prefix() TemplateModePrefix {
+	return TemplateModeContextPrefix
+}
+
+// FilesForParsing returns context files + cascading context files + layout + main files.
+func (contextMode) FilesForParsing(tm *TemplateManager, te *TemplateEnv) []string {
+	contextFiles := tm.GetContextFiles()
+	if len(te.Names) > 0 {
+		for _, f := range tm.getCascadingContextFiles(te.Names[0]) {
+			// cascading layouts are skipped if the layout option is set.
+			if te.Layout == "" || path.Base(f) != path.Base(tm.Config.FilePathOfLayoutRelativeToRoot) {
+				contextFiles = append(contextFiles, f)
+			}
+		}
+	}
+	if layout := tm.GetFilePathOfLayout(te); !ContainsString(contextFiles, layout) {
+		contextFiles = append(contextFiles, layout)
+	}
+	return append(contextFiles, te.GetFilePaths(tm.Config.DirOfRoot)...)
+}
+
+func (contextMode) EntryName(tm *TemplateManager, te *TemplateEnv) string {
+	return te.GetEntryName(filepath.Base(tm.GetFilePathOfLayout(te)))
+}
+
+type filesMode struct{}
+
+func (filesMode) Prefix() TemplateModePrefix {
+	return TemplateModeFilesPrefix
+}
+
+func (filesMode) FilesForParsing(tm *TemplateManager, te *TemplateEnv) []string {
+	return te.GetFilePaths(tm.Config.DirOfRoot)
+}
+
+func (filesMode) EntryName(tm *TemplateManager, te *TemplateEnv) string {
+	return te.GetEntryName(filepath.Base(te.Names[0]))
+}
+
+func defaultModes() []Mode {
+	return []Mode{contextMode{}, filesMode{}, stringMode{}}
+}
+
+// RegisterMode registers a mode. The prefix must be unique(and not be a prefix of another one).
+func (tm *TemplateManager) RegisterMode(mode Mode) error {
+	prefix := string(mode.Prefix())
+	if strings.Trim(prefix, " ") == "" {
+		return fmt.Errorf("mode prefix can not be empty: %T", mode)
+	}
+
+	tm.modeMutex.Lock()
+	for _, m := range tm.modes {
+		p := string(m.Prefix())
+		if strings.HasPrefix(p, prefix) || strings.HasPrefix(prefix, p) {
+			tm.modeMutex.Unlock()
+			return fmt.Errorf("mode prefix %q(%T) conflicts with registered mode prefix %q(%T)", prefix, mode, p, m)
+		}
+	}
+	tm.modes = append(tm.modes, mode)
+	tm.modeMutex.Unlock()
+	tm.removeTenants()
+	return nil
+}
+
+// GetModes returns the registered modes.
+func (tm *TemplateManager) GetModes() []Mode {
+	tm.modeMutex.RLock()
+	defer tm.modeMutex.RUnlock()
+	return append([]Mode(nil), tm.modes...)
+}
+
+// GetMode returns the registered mode of the prefix.
+func (tm *TemplateManager) GetMode(prefix TemplateModePrefix) (Mode, bool) {
+	for _, m := range tm.GetModes() {
+		if m.Prefix() == prefix {
+			return m, true
+		}
+	}
+	return nil, false
+}
+
+func (tm *TemplateManager) getModePrefixes() []TemplateModePrefix {
+	var prefixes []TemplateModePrefix
+	for _, m := range tm.GetModes() {
+		prefixes = append(prefixes, m.Prefix())
+	}
+	return prefixes
+}
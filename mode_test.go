@@ -0,0 +1,53 @@
+package templatemanager
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// componentMode renders a main file with context files but without executing the layout.
+type componentMode struct{}
+
+func (componentMode) Prefix() TemplateModePrefix {
+	return "P->"
+}
+
+func (componentMode) FilesForParsing(tm *TemplateManager, te *TemplateEnv) []string {
+	return append(tm.GetContextFiles(), te.GetFilePaths(tm.Config.DirOfRoot)...)
+}
+
+func (componentMode) EntryName(tm *TemplateManager, te *TemplateEnv) string {
+	return te.GetEntryName(filepath.Base(te.Names[0]))
+}
+
+func TestTemplateManager_RegisterMode(t *testing.T) {
+	tm := NewDefault(false)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.RegisterMode(componentMode{}); err != nil {
+		t.Fatalf("RegisterMode() error = %v", err)
+	}
+	if err := tm.RegisterMode(componentMode{}); err == nil {
+		t.Errorf("RegisterMode() expects an error of duplicated prefix")
+	}
+	if err := tm.RegisterMode(filesMode{}); err == nil {
+		t.Errorf("RegisterMode() expects an error of duplicated prefix")
+	}
+
+	out := &bytes.Buffer{}
+	if err := tm.ExecuteTemplate(out, "P->demo/demo1@content", nil); err != nil {
+		t.Fatalf("ExecuteTemplate() error = %v", err)
+	}
+	if got := out.String(); !strings.Contains(got, "Hi, This is a demo") || strings.Contains(got, "<html") {
+		t.Errorf("ExecuteTemplate() got unexpected output: \n%s", got)
+	}
+	if _, ok := tm.GetTemplate("P->main/demo/demo1.tpl.html@content"); !ok {
+		t.Errorf("GetTemplate() could not find the template of the registered mode")
+	}
+	if !strings.Contains(tm.Report(), `"P->" -> templatemanager.componentMode`) {
+		t.Errorf("Report() does not list the registered mode")
+	}
+}
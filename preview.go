@@ -0,0 +1,180 @@
+package templatemanager
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Preview server.
+//
+// PreviewHandler lists all parsed templates(GetTemplateNames) with the scenarios of their sidecar data files,
+// and renders any of them with the data of a scenario, see: SidecarScenarios.
+//
+//	GET /                                                          list templates and lint errors
+//	GET /render?name=C->main/demo/demo1.tpl.html&scenario=empty    render a template
+//
+// Changed templates are reloaded before every request, lint errors are cached until templates change.
+
+var previewIndexTemplate = template.Must(template.New("preview").Parse(`<!doctype html>
+<html>
+<head><meta charset="UTF-8"><title>Templates preview</title></head>
+<body>
+<h1>Templates ({{ len .Templates }})</h1>
+<ul>
+{{- range .Templates }}
+	<li><a href="{{ .URL }}">{{ .Name }}</a>{{ range .Scenarios }} | <a href="{{ .URL }}">{{ .Name }}</a>{{ end }}</li>
+{{- end }}
+</ul>
+{{- if .Issues }}
+<h2>Errors ({{ len .Issues }})</h2>
+<ul>
+{{- range .Issues }}
+	<li><pre>{{ . }}</pre></li>
+{{- end }}
+</ul>
+{{- end }}
+</body>
+</html>
+`))
+
+type previewLink struct {
+	Name      string
+	URL       string
+	Scenarios []previewLink
+}
+
+func previewURL(name string, scenario string) string {
+	values := url.Values{"name": []string{name}}
+	if scenario != "" {
+		values.Set("scenario", scenario)
+	}
+	return "render?" + values.Encode()
+}
+
+// PreviewHandler returns the handler of the preview server, see: "Preview server". Call Init(or Prepare) first.
+func (tm *TemplateManager) PreviewHandler() http.Handler {
+	// requests are served one by one, Lint and reloading change the manager.
+	var mutex sync.Mutex
+	// Lint re-parses every template, its errors are cached by the version of templates(see: getLiveReloadVersion).
+	var lintErrs []string
+	lintVersion := ""
+	reload := func() {
+		if tm.reloadForPreview() {
+			lintVersion = ""
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		reload()
+
+		if version := tm.getLiveReloadVersion(); version != lintVersion {
+			lintErrs = nil
+			for _, issue := range tm.Lint() {
+				if issue.Level == LintLevelError {
+					lintErrs = append(lintErrs, issue.String())
+				}
+			}
+			lintVersion = version
+		}
+		tm.templateMutex.RLock()
+		names := tm.GetTemplateNames()
+		tm.templateMutex.RUnlock()
+		sort.Strings(names)
+		var links []previewLink
+		for _, name := range names {
+			link := previewLink{Name: name, URL: previewURL(name, "")}
+			te := newTemplateEnvByParsing(name, tm.getModePrefixes()...)
+			if len(te.Names) > 0 {
+				scenarios, _ := tm.SidecarScenarios(te.Names[0])
+				for _, scenario := range GetScenarioNames(scenarios) {
+					link.Scenarios = append(link.Scenarios, previewLink{Name: scenario, URL: previewURL(name, scenario)})
+				}
+			}
+			links = append(links, link)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := previewIndexTemplate.Execute(w, map[string]interface{}{"Templates": links, "Issues": lintErrs})
+		if err != nil {
+			log.Printf("Preview index error: %s", err)
+		}
+	})
+	mux.HandleFunc("/render", func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		reload()
+
+		name := r.URL.Query().Get("name")
+		scenario := r.URL.Query().Get("scenario")
+		buf, status, err := tm.renderPreview(name, scenario)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		buf.WriteTo(w)
+	})
+	return mux
+}
+
+// reloadForPreview reloads changed templates, it returns true if any template changed(or failed reloading).
+func (tm *TemplateManager) reloadForPreview() bool {
+	changed, err := tm.ReloadChanged()
+	if err != nil {
+		log.Printf("Preview reloading error: %s", err)
+	}
+	return err != nil || len(changed) > 0
+}
+
+// renderPreview renders the template with the data of the scenario, status is the http status of the error.
+func (tm *TemplateManager) renderPreview(name string, scenario string) (buf *bytes.Buffer, status int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			buf, status, err = nil, http.StatusInternalServerError, fmt.Errorf("%v", r)
+		}
+	}()
+	if strings.TrimSpace(name) == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("template name is required")
+	}
+	te, err := tm.NewTemplateEnv(name)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	if len(te.Names) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid template name: %q", name)
+	}
+	if tenant, ok := te.Options[OptionTenantKey]; ok {
+		if err := checkTenant(tenant); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+	scenarios, err := tm.SidecarScenarios(te.Names[0])
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if scenario == "" {
+		if scenario = DefaultScenario; scenarios[scenario] == nil && len(scenarios) > 0 {
+			scenario = GetScenarioNames(scenarios)[0]
+		}
+	} else if _, ok := scenarios[scenario]; !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("scenario %q of template %q does not exist, scenarios are: %q", scenario, name, GetScenarioNames(scenarios))
+	}
+	data := scenarios[scenario]
+	buf = &bytes.Buffer{}
+	if err := tm.ExecuteTemplate(buf, name, data); err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return buf, http.StatusOK, nil
+}
@@ -0,0 +1,86 @@
+package templatemanager
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestTemplateManager_PreviewHandler(t *testing.T) {
+	conf := newTestConfig(t, false, map[string]string{
+		"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`,
+		"main/demo/demo1.tpl.html":       `{{ define "content" }}hi {{ .name }}{{ end }}`,
+		"main/demo/demo1.tpl.json":       `{"scenarios": {"default": {"name": "alice"}, "empty": {"name": ""}}}`,
+		"main/demo/demo2.tpl.html":       `{{ define "content" }}{{ .title }}{{ end }}`,
+		"main/demo/demo2.yaml":           "title: demo2 from yaml\n",
+	})
+	tm := New(conf)
+	if err := tm.Prepare(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(tm.PreviewHandler())
+	defer server.Close()
+
+	get := func(p string) (int, string) {
+		resp, err := http.Get(server.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+	render := func(name, scenario string) string {
+		return "/render?" + url.Values{"name": {name}, "scenario": {scenario}}.Encode()
+	}
+
+	status, body := get("/")
+	for _, want := range []string{"C-&gt;main/demo/demo1.tpl.html", "F-&gt;main/demo/demo2.tpl.html", ">empty</a>", ">default</a>"} {
+		if status != http.StatusOK || !strings.Contains(body, want) {
+			t.Errorf("GET / = %d %q, want %q", status, body, want)
+		}
+	}
+
+	tests := []struct {
+		path       string
+		wantStatus int
+		want       string
+	}{
+		{render("C->main/demo/demo1.tpl.html", ""), http.StatusOK, "<html>hi alice</html>"},
+		{render("demo/demo1", "empty"), http.StatusOK, "<html>hi </html>"},
+		{render("F->demo/demo1", "default"), http.StatusOK, ""},
+		{render("demo/demo2", ""), http.StatusOK, "<html>demo2 from yaml</html>"},
+		{render("demo/demo1", "none"), http.StatusNotFound, `scenario "none"`},
+		{render("demo/none", ""), http.StatusNotFound, "could not resolve template name"},
+		{"/render", http.StatusBadRequest, "template name is required"},
+		{render("demo/demo1?layout=context/layout/none.tpl.html", ""), http.StatusNotFound, "could not find layout"},
+		{render("demo/demo1?tenant=..", ""), http.StatusBadRequest, "invalid tenant"},
+		{render("F->", ""), http.StatusNotFound, "could not resolve template name"},
+	}
+	for _, tt := range tests {
+		if status, body := get(tt.path); status != tt.wantStatus || !strings.Contains(body, tt.want) {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.path, status, body, tt.wantStatus, tt.want)
+		}
+	}
+
+	// changed templates are re-rendered.
+	writeTemplateFiles(t, conf.DirOfRoot, map[string]string{"main/demo/demo1.tpl.html": `{{ define "content" }}hello {{ .name }}{{ end }}`})
+	if _, body := get(render("demo/demo1", "")); body != "<html>hello alice</html>" {
+		t.Errorf("GET demo1 after changing = %q", body)
+	}
+
+	// lint errors are cached until templates change.
+	get("/")
+	cached, _ := tm.GetTemplate("C->main/demo/demo1.tpl.html")
+	get("/")
+	if tpl, _ := tm.GetTemplate("C->main/demo/demo1.tpl.html"); tpl != cached {
+		t.Errorf("GET / should not lint again if templates are not changed")
+	}
+	writeTemplateFiles(t, conf.DirOfRoot, map[string]string{"main/demo/demo3.tpl.html": `{{ define "content" }}{{ if }}{{ end }}`})
+	if _, body := get("/"); !strings.Contains(body, "main/demo/demo3.tpl.html") {
+		t.Errorf("GET / after adding a broken template = %q, want its lint error", body)
+	}
+}
@@ -0,0 +1,191 @@
+package templatemanager
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Template name resolution.
+//
+// Besides the canonical name(file path relative to DirOfRoot), a template can be referred by a short name:
+//
+//	"main/demo/demo1.tpl.html" (canonical)
+//	"main/demo/demo1.tpl"      (without Extension, or any of Extensions)
+//	"main/demo/demo1"          (without any extension)
+//	"demo/demo1.tpl.html"      (without DirOfMainRelativeToRoot)
+//	"demo/demo1"               (without DirOfMainRelativeToRoot and any extension)
+//
+// A short name matching more than one template file is an error.
+
+// trimAllExt trims every extension of the file name. eg: "a/b.tpl.html" -> "a/b"
+func trimAllExt(p string) string {
+	dir, base := path.Split(p)
+	if i := strings.Index(base, "."); i > 0 {
+		base = base[:i]
+	}
+	return dir + base
+}
+
+// getShortNames returns all names which could be used to refer to the template(basic name).
+func (tm *TemplateManager) getShortNames(basicName string) []string {
+	names := []string{basicName}
+	for _, ext := range tm.getExtensions() {
+		if n := strings.TrimSuffix(basicName, ext); n != basicName && !ContainsString(names, n) {
+			names = append(names, n)
+		}
+	}
+	if n := trimAllExt(basicName); !ContainsString(names, n) {
+		names = append(names, n)
+	}
+	mainPrefix := strings.Trim(path.Clean(tm.Config.DirOfMainRelativeToRoot), "/") + "/"
+	if strings.HasPrefix(basicName, mainPrefix) {
+		for _, n := range append([]string(nil), names...) {
+			names = append(names, strings.TrimPrefix(n, mainPrefix))
+		}
+	}
+	return names
+}
+
+// buildNameIndex indexes every main template and string template by all of its short names.
+func (tm *TemplateManager) buildNameIndex() map[string][]string {
+	index := make(map[string][]string)
+	var basicNames []string
+	for _, f := range tm.getMainFiles() {
+		basicNames = append(basicNames, tm.getBasicTemplateNameByFilePath(f))
+	}
+	for _, basicName := range append(basicNames, tm.GetStringTemplateNames()...) {
+		for _, n := range tm.getShortNames(basicName) {
+			if !ContainsString(index[n], basicName) {
+				index[n] = append(index[n], basicName)
+			}
+		}
+	}
+	return index
+}
+
+func (tm *TemplateManager) lookupNameIndex(name string, rebuild bool) []string {
+	tm.resolveMutex.Lock()
+	defer tm.resolveMutex.Unlock()
+	if tm.nameIndex == nil || rebuild {
+		tm.nameIndex = tm.buildNameIndex()
+	}
+	return tm.nameIndex[name]
+}
+
+// resolveName resolves a (short) name to the basic template name(file path relative to DirOfRoot).
+// String templates take precedence over files, onlyStrings limits the resolution to string templates.
+func (tm *TemplateManager) resolveName(name string, onlyStrings bool) (string, error) {
+	name = strings.TrimPrefix(path.Clean(name), "/")
+	filePath := path.Join(tm.Config.DirOfRoot, name)
+	if _, ok := tm.getStringTemplate(filePath); ok {
+		return name, nil
+	}
+	if !onlyStrings {
+		if _, err := tm.GetLoader().Stat(name); err == nil {
+			return name, nil
+		}
+	}
+
+	matches := tm.lookupNameIndex(name, false)
+	if len(matches) == 0 && tm.Config.IsDebugging {
+		// templates might be added after Init in debug mode.
+		matches = tm.lookupNameIndex(name, true)
+	}
+	if onlyStrings {
+		var stringMatches []string
+		for _, m := range matches {
+			if _, ok := tm.getStringTemplate(path.Join(tm.Config.DirOfRoot, m)); ok {
+				stringMatches = append(stringMatches, m)
+			}
+		}
+		matches = stringMatches
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("could not resolve template name: %q (root: %q)", name, tm.Config.DirOfRoot)
+	case 1:
+		return matches[0], nil
+	default:
+		sorted := append([]string(nil), matches...)
+		sort.Strings(sorted)
+		return "", fmt.Errorf("ambiguous template name: %q, candidates are: %q", name, sorted)
+	}
+}
+
+// NewTemplateEnv parses the templateName, then resolves every name in it to the canonical one.
+func (tm *TemplateManager) NewTemplateEnv(templateName string) (*TemplateEnv, error) {
+	te := newTemplateEnvByParsing(templateName, tm.getModePrefixes()...)
+	// templates are cached by names with options, so arbitrary options could grow the cache without bound.
+	for key := range te.Options {
+		if key != OptionTenantKey {
+			return nil, fmt.Errorf("unknown option %q of template name: %q", key, templateName)
+		}
+	}
+	if tm.tenant != "" {
+		// templates of a tenant manager are always cached by tenant + standard name.
+		if te.Options == nil {
+			te.Options = make(map[string]string)
+		}
+		te.Options[OptionTenantKey] = tm.tenant
+	}
+	requestedName := te.StandardTemplateName()
+	if !tm.Config.IsDebugging {
+		tm.resolveMutex.RLock()
+		canonical, ok := tm.aliases[requestedName]
+		tm.resolveMutex.RUnlock()
+		if ok {
+			return newTemplateEnvByParsing(canonical, tm.getModePrefixes()...), nil
+		}
+	}
+
+	for i, name := range te.Names {
+		resolved, err := tm.resolveName(name, te.Mode == TemplateModeStringPrefix)
+		if err != nil {
+			return nil, err
+		}
+		if te.Entry == name {
+			te.Entry = resolved
+		}
+		te.Names[i] = resolved
+	}
+	if te.Layout != "" {
+		layout, err := tm.resolveLayout(te.Layout)
+		if err != nil {
+			return nil, err
+		}
+		te.Layout = layout
+	}
+
+	if !tm.Config.IsDebugging {
+		tm.resolveMutex.Lock()
+		tm.aliases[requestedName] = te.StandardTemplateName()
+		tm.resolveMutex.Unlock()
+	}
+	return te, nil
+}
+
+// resolveLayout cleans the layout option(path relative to root), and checks that it exists in string templates or the loader.
+func (tm *TemplateManager) resolveLayout(layout string) (string, error) {
+	name := strings.TrimPrefix(path.Clean("/"+layout), "/")
+	if name == "" {
+		return "", fmt.Errorf("layout can not be empty")
+	}
+	if _, ok := tm.getStringTemplate(path.Join(tm.Config.DirOfRoot, name)); ok {
+		return name, nil
+	}
+	if _, err := tm.GetLoader().Stat(name); err != nil {
+		return "", fmt.Errorf("could not find layout: %q (root: %q)", layout, tm.Config.DirOfRoot)
+	}
+	return name, nil
+}
+
+// Resolve returns the canonical(standard) template name and the files for parsing of the templateName, without rendering.
+func (tm *TemplateManager) Resolve(templateName string) (string, []string, error) {
+	te, err := tm.NewTemplateEnv(templateName)
+	if err != nil {
+		return "", nil, err
+	}
+	return te.StandardTemplateName(), tm.getFilesForParsing(te), nil
+}
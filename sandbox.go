@@ -0,0 +1,311 @@
+package templatemanager
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sync"
+	"text/template/parse"
+	"time"
+)
+
+// Sandbox restricts user-authored templates(eg: templates uploaded by tenants).
+//
+// A sandboxed template file:
+//   - could only call builtin functions and AllowedFuncs.
+//   - could only call templates({{ template "x" }}) defined in sandboxed files.
+//   - could only include(if allowed) sandboxed templates by constant names.
+//
+// Rendering a template which contains sandboxed files is limited by MaxOutputBytes and Timeout.
+type Sandbox struct {
+	Dirs           []string      // sandboxed template dirs relative to root. eg: ["main/user"]
+	Loader         Loader        // templates which exist in this loader are sandboxed. eg: the loader of a tenant
+	AllowedFuncs   []string      // functions of FuncMap which could be called. eg: ["include", "time_isoformat"]
+	MaxOutputBytes int           // 0: unlimited
+	Timeout        time.Duration // 0: unlimited. the execution stops at its next write after timeout, see: executeSandboxed
+}
+
+var ErrSandboxOutputTooLarge = errors.New("sandbox: output exceeds MaxOutputBytes")
+var ErrSandboxTimeout = errors.New("sandbox: execution timeout")
+
+// builtin functions of text/template.
+var sandboxBuiltinFuncs = []string{
+	"and", "call", "html", "index", "slice", "js", "len", "not", "or",
+	"print", "printf", "println", "urlquery", "eq", "ge", "gt", "le", "lt", "ne",
+}
+
+// SetSandbox sets the sandbox, nil disables it.
+func (tm *TemplateManager) SetSandbox(sandbox *Sandbox) {
+	tm.sandboxMutex.Lock()
+	tm.sandbox = sandbox
+	tm.sandboxMutex.Unlock()
+	tm.removeTenants()
+}
+
+// SetTenantSandbox sandboxes templates of every tenant layer, see: GetTenantManager.
+func (tm *TemplateManager) SetTenantSandbox(sandbox *Sandbox) {
+	tm.sandboxMutex.Lock()
+	tm.tenantSandbox = sandbox
+	tm.sandboxMutex.Unlock()
+	tm.removeTenants()
+}
+
+func (tm *TemplateManager) getSandbox() *Sandbox {
+	tm.sandboxMutex.RLock()
+	defer tm.sandboxMutex.RUnlock()
+	return tm.sandbox
+}
+
+// IsSandboxed checks if the template(basic name) is sandboxed.
+func (tm *TemplateManager) IsSandboxed(basicName string) bool {
+	sandbox := tm.getSandbox()
+	if sandbox == nil {
+		return false
+	}
+	for _, dir := range sandbox.Dirs {
+		if isInDir(basicName, dir) {
+			return true
+		}
+	}
+	if sandbox.Loader != nil {
+		if _, err := sandbox.Loader.Stat(basicName); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isSandboxedTemplate checks if any file of the parsed template is sandboxed.
+func (tm *TemplateManager) isSandboxedTemplate(tplName string) bool {
+	tm.versionMutex.RLock()
+	defer tm.versionMutex.RUnlock()
+	return tm.sandboxedTemplates[tplName]
+}
+
+// markSandboxedTemplate records whether any file of the template is sandboxed.
+func (tm *TemplateManager) markSandboxedTemplate(tplName string, filesForParsing []string) {
+	sandboxed := false
+	for _, f := range filesForParsing {
+		if tm.IsSandboxed(tm.getBasicTemplateNameByFilePath(f)) {
+			sandboxed = true
+			break
+		}
+	}
+	tm.versionMutex.Lock()
+	defer tm.versionMutex.Unlock()
+	tm.sandboxedTemplates[tplName] = sandboxed
+}
+
+func (tm *TemplateManager) getSandboxFuncs() map[string]interface{} {
+	funcs := make(map[string]interface{})
+	for _, name := range sandboxBuiltinFuncs {
+		funcs[name] = true
+	}
+	for _, name := range tm.getSandbox().AllowedFuncs {
+		funcs[name] = true
+	}
+	return funcs
+}
+
+// ValidateSandboxed validates a sandboxed template(eg: an upload) before storing it.
+// name is the path relative to root, definedNames are the templates which could be called besides the ones defined in src.
+func (tm *TemplateManager) ValidateSandboxed(name string, src string, definedNames ...string) error {
+	if tm.getSandbox() == nil {
+		return fmt.Errorf("sandbox is not set")
+	}
+	left, right := tm.Config.Delims.get()
+	trees, err := parse.Parse(path.Base(name), src, left, right, tm.getSandboxFuncs())
+	if err != nil {
+		return fmt.Errorf("sandbox: template %q: %s", name, err)
+	}
+	allowed := append([]string{path.Base(name)}, definedNames...)
+	for treeName := range trees {
+		allowed = append(allowed, treeName)
+	}
+	for _, tree := range trees {
+		if err := tm.checkSandboxedNode(name, tree.Root, allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSandboxedFiles validates the sandboxed files of filesForParsing.
+// Templates defined in sandboxed files could call each other.
+func (tm *TemplateManager) validateSandboxedFiles(filesForParsing []string) error {
+	sources := make(map[string]string)
+	var definedNames []string
+	for _, f := range filesForParsing {
+		if !tm.IsSandboxed(tm.getBasicTemplateNameByFilePath(f)) {
+			continue
+		}
+		b, err := tm.readTemplateFile(f)
+		if err != nil {
+			return err
+		}
+		sources[f] = string(b)
+		left, right := tm.Config.Delims.get()
+		trees, err := parse.Parse(path.Base(f), string(b), left, right, tm.getSandboxFuncs())
+		if err != nil {
+			return fmt.Errorf("sandbox: template %q: %s", tm.getBasicTemplateNameByFilePath(f), err)
+		}
+		for treeName := range trees {
+			definedNames = append(definedNames, treeName)
+		}
+	}
+	for f, src := range sources {
+		if err := tm.ValidateSandboxed(tm.getBasicTemplateNameByFilePath(f), src, definedNames...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tm *TemplateManager) checkSandboxedNode(name string, node parse.Node, allowed []string) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := tm.checkSandboxedNode(name, child, allowed); err != nil {
+				return err
+			}
+		}
+	case *parse.TemplateNode:
+		if !ContainsString(allowed, n.Name) {
+			return fmt.Errorf("sandbox: template %q: line %d: calling template %q which is outside the sandbox", name, n.Line, n.Name)
+		}
+		return tm.checkSandboxedNode(name, n.Pipe, allowed)
+	case *parse.ActionNode:
+		return tm.checkSandboxedNode(name, n.Pipe, allowed)
+	case *parse.ChainNode:
+		// eg: {{ (include .name .).Field }}
+		return tm.checkSandboxedNode(name, n.Node, allowed)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := tm.checkSandboxedNode(name, cmd, allowed); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		if len(n.Args) > 0 {
+			if ident, ok := n.Args[0].(*parse.IdentifierNode); ok && ident.Ident == "include" {
+				if err := tm.checkSandboxedInclude(name, n); err != nil {
+					return err
+				}
+			}
+		}
+		for _, arg := range n.Args {
+			if err := tm.checkSandboxedNode(name, arg, allowed); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return tm.checkSandboxedBranch(name, &n.BranchNode, allowed)
+	case *parse.RangeNode:
+		return tm.checkSandboxedBranch(name, &n.BranchNode, allowed)
+	case *parse.WithNode:
+		return tm.checkSandboxedBranch(name, &n.BranchNode, allowed)
+	}
+	return nil
+}
+
+func (tm *TemplateManager) checkSandboxedBranch(name string, n *parse.BranchNode, allowed []string) error {
+	for _, node := range []parse.Node{n.Pipe, n.List, n.ElseList} {
+		if err := tm.checkSandboxedNode(name, node, allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSandboxedInclude checks {{ include "name" . }}: name must be a constant and all its files must be sandboxed.
+func (tm *TemplateManager) checkSandboxedInclude(name string, n *parse.CommandNode) error {
+	if len(n.Args) < 2 {
+		return nil
+	}
+	s, ok := n.Args[1].(*parse.StringNode)
+	if !ok {
+		return fmt.Errorf("sandbox: template %q: %q: the name of include must be a constant string", name, n.String())
+	}
+	te, err := tm.NewTemplateEnv(s.Text)
+	if err != nil {
+		return fmt.Errorf("sandbox: template %q: %q: %s", name, n.String(), err)
+	}
+	for _, includedName := range te.Names {
+		if !tm.IsSandboxed(includedName) {
+			return fmt.Errorf("sandbox: template %q: including template %q which is outside the sandbox", name, includedName)
+		}
+	}
+	return nil
+}
+
+func (tm *TemplateManager) tryParseMainTemplateByFilePath(filePath string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	tm.parseMainTemplateByFilePath(filePath)
+	return nil
+}
+
+// sandboxWriter writes at most max bytes, and stops writing after timeout.
+type sandboxWriter struct {
+	buf      bytes.Buffer
+	max      int
+	timedOut bool
+	mutex    sync.Mutex
+}
+
+func (w *sandboxWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.timedOut {
+		return 0, ErrSandboxTimeout
+	}
+	if w.max > 0 && w.buf.Len()+len(p) > w.max {
+		return 0, ErrSandboxOutputTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+// executeSandboxed executes within the limits of the sandbox, output is written to out only if it succeeds.
+// An execution could not be cancelled: after timeout, the output is discarded and the next write of the execution
+// fails(which stops it), but the goroutine keeps running until then, eg: a long range which writes nothing.
+func (tm *TemplateManager) executeSandboxed(execute func(out io.Writer) error, out io.Writer) error {
+	sandbox := tm.getSandbox()
+	w := &sandboxWriter{max: sandbox.MaxOutputBytes}
+	done := make(chan error, 1)
+	go func() {
+		done <- execute(w)
+	}()
+
+	var timeout <-chan time.Time
+	if sandbox.Timeout > 0 {
+		timer := time.NewTimer(sandbox.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+	case <-timeout:
+		w.mutex.Lock()
+		w.timedOut = true
+		w.mutex.Unlock()
+		log.Printf("Sandboxed template execution timeout: %s", sandbox.Timeout)
+		return ErrSandboxTimeout
+	}
+	_, err := w.buf.WriteTo(out)
+	return err
+}
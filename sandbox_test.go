@@ -0,0 +1,96 @@
+package templatemanager
+
+import (
+	"bytes"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateManager_Sandbox(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "templates")
+	writeTemplateFiles(t, root, map[string]string{
+		"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`,
+		"context/partial/ads.tpl.html":   `{{ define "ads" }}{{ secret }}{{ end }}`,
+		"main/home.tpl.html":             `{{ define "content" }}home {{ template "ads" . }}{{ end }}`,
+		"main/user/ok.tpl.html":          `{{ define "content" }}{{ upper .name }} {{ template "sign" . }}{{ end }}{{ define "sign" }}sign{{ end }}`,
+		"main/user/footer.tpl.html":      `{{ define "footer" }}footer{{ end }}`,
+		"main/user/page.tpl.html":        `{{ define "content" }}{{ include "user/footer@footer" . }}{{ end }}`,
+		"main/user/secret.tpl.html":      `{{ define "content" }}{{ secret }}{{ end }}`,
+		"main/user/ads.tpl.html":         `{{ define "content" }}{{ template "ads" . }}{{ end }}`,
+		"main/user/include.tpl.html":     `{{ define "content" }}{{ include "home" . }}{{ end }}`,
+		"main/user/big.tpl.html":         `{{ define "content" }}{{ range .items }}0123456789{{ end }}{{ end }}`,
+		"main/user/slow.tpl.html":        `{{ define "content" }}{{ slow }}{{ end }}`,
+	})
+
+	conf := NewDefaultConfig(false)
+	conf.DirOfRoot = root
+	conf.FuncMap = template.FuncMap{
+		"upper":  strings.ToUpper,
+		"secret": func() string { return "secret" },
+		"slow":   func() string { time.Sleep(200 * time.Millisecond); return "slow" },
+	}
+	tm := New(conf)
+	tm.SetSandbox(&Sandbox{
+		Dirs:           []string{"main/user"},
+		AllowedFuncs:   []string{"upper", "include", "slow"},
+		MaxOutputBytes: 100,
+		Timeout:        50 * time.Millisecond,
+	})
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		templateName string
+		data         interface{}
+		want         string
+		wantErr      string
+	}{
+		{"not sandboxed", "home", nil, "<html>home secret</html>", ""},
+		{"allowed funcs and templates", "user/ok", map[string]string{"name": "acme"}, "<html>ACME sign</html>", ""},
+		{"include a sandboxed template", "user/page", nil, "<html>footer</html>", ""},
+		{"not allowed func", "user/secret", nil, "", `function "secret" not defined`},
+		{"template outside the sandbox", "user/ads", nil, "", `calling template "ads" which is outside the sandbox`},
+		{"include outside the sandbox", "user/include", nil, "", `including template "main/home.tpl.html" which is outside the sandbox`},
+		{"output too large", "user/big", map[string]interface{}{"items": make([]int, 20)}, "", ErrSandboxOutputTooLarge.Error()},
+		{"timeout", "user/slow", nil, "", ErrSandboxTimeout.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := tm.ExecuteTemplate(out, tt.templateName, tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ExecuteTemplate() error = %v, want %q", err, tt.wantErr)
+				}
+				if out.Len() != 0 {
+					t.Errorf("ExecuteTemplate() should not write output on error, got: %q", out.String())
+				}
+				return
+			}
+			if err != nil || out.String() != tt.want {
+				t.Errorf("ExecuteTemplate() got = %q, err = %v, want %q", out.String(), err, tt.want)
+			}
+		})
+	}
+
+	err := tm.ValidateSandboxed("main/user/upload.tpl.html", `{{ define "content" }}{{ template "footer" . }}{{ end }}`)
+	if err == nil || !strings.Contains(err.Error(), `calling template "footer"`) {
+		t.Errorf("ValidateSandboxed() error = %v", err)
+	}
+	if err := tm.ValidateSandboxed("main/user/upload.tpl.html", `{{ define "content" }}{{ template "footer" . }}{{ end }}`, "footer"); err != nil {
+		t.Errorf("ValidateSandboxed() error = %v", err)
+	}
+	if err := tm.ValidateSandboxed("main/user/upload.tpl.html", `{{ if }}`); err == nil {
+		t.Errorf("ValidateSandboxed() expects a parse error")
+	}
+	// a non-constant include in a chain.
+	err = tm.ValidateSandboxed("main/user/upload.tpl.html", `{{ define "content" }}{{ (include .name .).X }}{{ end }}`)
+	if err == nil || !strings.Contains(err.Error(), "must be a constant string") {
+		t.Errorf("ValidateSandboxed() of a chained include error = %v", err)
+	}
+}
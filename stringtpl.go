@@ -0,0 +1,127 @@
+package templatemanager
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+const TemplateModeStringPrefix TemplateModePrefix = "S->"
+
+// String templates are templates registered from go code, they are treated as files under DirOfRoot:
+// they share the cache, the FuncMap and the "include" helper with template files, and a string template
+// under DirOfContextRelativeToRoot is a context template.
+//
+//	tm.AddTemplateString("S->widgets/banner", `{{ define "content" }}banner{{ end }}`)
+//
+//	"S->widgets/banner"   (StringMode: ContextMode which only looks up string templates)
+//	"C->widgets/banner"   (ContextMode)
+//	"F->widgets/banner"   (FilesMode)
+
+// stringMode is the same as ContextMode, except that names are resolved in string templates only.
+type stringMode struct {
+	contextMode
+}
+
+func (stringMode) Prefix() TemplateModePrefix {
+	return TemplateModeStringPrefix
+}
+
+// AddTemplateString registers(or replaces) a template from string. name is the path relative to root,
+// prefix "S->" is optional.
+func (tm *TemplateManager) AddTemplateString(name string, src string) error {
+	name = strings.TrimPrefix(strings.Trim(name, " "), string(TemplateModeStringPrefix))
+	name = strings.TrimPrefix(path.Clean(strings.Trim(name, " ")), "/")
+	if name == "" || name == "." {
+		return fmt.Errorf("string template name can not be empty")
+	}
+
+	// validate the source. "include" might not be added before Init.
+	funcMap := template.FuncMap{"include": func(string, interface{}) (template.HTML, error) { return "", nil }}
+	for k, v := range tm.getFuncMap() {
+		funcMap[k] = v
+	}
+	if _, err := template.New(name).Delims(tm.Config.Delims.get()).Funcs(funcMap).Parse(src); err != nil {
+		return fmt.Errorf("could not parse string template: %q. err: %s", name, err)
+	}
+
+	filePath := path.Join(tm.Config.DirOfRoot, name)
+	tm.stringMutex.Lock()
+	tm.stringTemplates[filePath] = src
+	tm.stringMutex.Unlock()
+
+	tm.resolveMutex.Lock()
+	if tm.nameIndex != nil {
+		for _, n := range tm.getShortNames(name) {
+			if !ContainsString(tm.nameIndex[n], name) {
+				tm.nameIndex[n] = append(tm.nameIndex[n], name)
+			}
+		}
+	}
+	if tm.cascadingIndex != nil && tm.isInCascadingContextDir(name) {
+		tm.addToCascadingIndex(tm.cascadingIndex, filePath)
+	}
+	tm.aliases = make(map[string]string)
+	tm.resolveMutex.Unlock()
+
+	tm.removeTemplatesOfFile(name)
+	tm.removeTenants()
+	if tm.DoShowDebugMessage() {
+		log.Printf("Added string template: %q", name)
+	}
+	return nil
+}
+
+// removeTemplatesOfFile removes the cached templates which are parsed from the file(basic name).
+// All cached templates are removed if the file is a context template.
+func (tm *TemplateManager) removeTemplatesOfFile(basicName string) {
+	isContextFile := isInDir(basicName, tm.Config.DirOfContextRelativeToRoot) || path.Clean(tm.Config.FilePathOfLayoutRelativeToRoot) == basicName ||
+		tm.isInCascadingContextDir(basicName)
+
+	tm.templateMutex.Lock()
+	defer tm.templateMutex.Unlock()
+	for tplName := range tm.TemplatesMap {
+		te := newTemplateEnvByParsing(tplName, tm.getModePrefixes()...)
+		if isContextFile || ContainsString(te.Names, basicName) || te.Layout == basicName {
+			delete(tm.TemplatesMap, tplName)
+			delete(tm.dataFields, tplName)
+		}
+	}
+}
+
+func (tm *TemplateManager) getStringTemplate(filePath string) (string, bool) {
+	tm.stringMutex.RLock()
+	defer tm.stringMutex.RUnlock()
+	src, ok := tm.stringTemplates[filePath]
+	return src, ok
+}
+
+// getStringTemplateFilePaths returns the sorted file paths of string templates under dir.
+func (tm *TemplateManager) getStringTemplateFilePaths(dir string) []string {
+	tm.stringMutex.RLock()
+	defer tm.stringMutex.RUnlock()
+	prefix := path.Clean(dir) + "/"
+	if prefix == "./" {
+		prefix = ""
+	}
+	var filePaths []string
+	for f := range tm.stringTemplates {
+		if strings.HasPrefix(f, prefix) {
+			filePaths = append(filePaths, f)
+		}
+	}
+	sort.Strings(filePaths)
+	return filePaths
+}
+
+// GetStringTemplateNames returns the names(path relative to root) of string templates.
+func (tm *TemplateManager) GetStringTemplateNames() []string {
+	var names []string
+	for _, f := range tm.getStringTemplateFilePaths(tm.Config.DirOfRoot) {
+		names = append(names, tm.getBasicTemplateNameByFilePath(f))
+	}
+	return names
+}
@@ -0,0 +1,63 @@
+package templatemanager
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTemplateManager_AddTemplateString(t *testing.T) {
+	tm := NewDefault(false)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+	banner := `{{ define "title" }}banner{{ end }}{{ define "content" }}<b>banner: {{ .name }}</b>{{ template "tip" . }}{{ end }}`
+	if err := tm.AddTemplateString("S->widgets/banner.html", banner); err != nil {
+		t.Fatalf("AddTemplateString() error = %v", err)
+	}
+	if err := tm.AddTemplateString("context/partial/tip.html", `{{ define "tip" }}<i>tip</i>{{ end }}`); err != nil {
+		t.Fatalf("AddTemplateString() error = %v", err)
+	}
+	if err := tm.AddTemplateString("widgets/page.html", `{{ define "title" }}page{{ end }}{{ define "content" }}{{ include "S->widgets/banner@content" . }}{{ end }}`); err != nil {
+		t.Fatalf("AddTemplateString() error = %v", err)
+	}
+	if err := tm.AddTemplateString("widgets/invalid.html", `{{ define "content" }}`); err == nil {
+		t.Errorf("AddTemplateString() expects a parse error")
+	}
+
+	data := map[string]interface{}{"name": "string"}
+	tests := []struct {
+		name         string
+		templateName string
+		want         []string
+		wantErr      bool
+	}{
+		{"StringMode with layout", "S->widgets/banner", []string{"<html", "<b>banner: string</b><i>tip</i>"}, false},
+		{"FilesMode", "F->widgets/banner.html@title", []string{"banner"}, false},
+		{"ContextMode with include", "widgets/page", []string{"<html", "<b>banner: string</b><i>tip</i>"}, false},
+		{"StringMode only looks up string templates", "S->demo/demo1", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := tm.ExecuteTemplate(out, tt.templateName, data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("ExecuteTemplate() got = \n%s\nwant contains: %q", out.String(), w)
+				}
+			}
+		})
+	}
+
+	// replacing a string template removes the cached templates.
+	if err := tm.AddTemplateString("widgets/banner.html", `{{ define "title" }}{{ end }}{{ define "content" }}new banner{{ end }}`); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := tm.ExecuteTemplate(out, "S->widgets/banner", data); err != nil || !strings.Contains(out.String(), "new banner") {
+		t.Errorf("ExecuteTemplate() got = %q, err = %v", out.String(), err)
+	}
+}
@@ -23,6 +23,23 @@ Go(Golang) template manager, especially suited for web.
      or "F-> main/demo/demo.tpl.html"
      or "F-> main/demo/demo.tpl.html;main/demo/demo_ads.tpl.html" (will use the first file name when executing template)
 
+3. StringMode: Name starts with "S->". The same as ContextMode, but only looks up templates added by `AddTemplateString`.
+
+Other modes could be registered by `RegisterMode`, see: `Mode`.
+
+# Entry, layout and options
+Full grammar: [mode-prefix] files [ "@" entry ] [ "?" key=value { "&" key=value } ]
+
+	eg: "F->main/demo/demo2.tpl.html;main/demo/demo1.tpl.html@main/demo/demo1.tpl.html" (execute file demo1.tpl.html)
+	 or "C->main/demo/demo1.tpl.html@content" (execute the defined template "content" only)
+	 or "main/demo/demo1.tpl.html?layout=context/layout/simple.tpl.html" (use another layout in ContextMode)
+
+# Short names
+A name could omit `DirOfMainRelativeToRoot` and the extension, it will be resolved to the canonical name.
+
+	eg: "demo/demo1" -> "main/demo/demo1.tpl.html"
+	 or "F->demo/demo2;demo/demo1" -> "F->main/demo/demo2.tpl.html;main/demo/demo1.tpl.html"
+
 # ContextMode is using template nesting, somewhat like template-inheritance in django/jinja2/...
 ContextMode will load context templates, then execute template in file: `FilePathOfLayoutRelativeToRoot`.
 
@@ -43,9 +60,8 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
-	"os"
 	"path"
-	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -89,28 +105,88 @@ func Version() string {
 type TemplateManager struct {
 	Config        TemplateConfig
 	TemplatesMap  map[string]*template.Template
+	dataFields    map[string][]string // standard template name -> data fields, see: GetDataFields
 	templateMutex sync.RWMutex
+
+	nameIndex      map[string][]string // short name -> basic template names
+	cascadingIndex map[string][]string // cascading context dir -> file paths, see: getCascadingContextFiles
+	aliases        map[string]string   // requested standard name -> canonical standard name
+	resolveMutex   sync.RWMutex
+
+	modes     []Mode
+	modeMutex sync.RWMutex
+
+	stringTemplates map[string]string // file path -> template source, see: AddTemplateString
+	stringMutex     sync.RWMutex
+
+	tenant       string                      // tenant of the manager, see: GetTenantManager
+	tenants      map[string]*TemplateManager // tenant -> manager of the tenant
+	tenantOrder  []string                    // least recently used tenant first
+	tenantLoader func(tenant string) Loader  // loader of the tenant layer
+	tenantMutex  sync.Mutex
+
+	sandbox            *Sandbox
+	tenantSandbox      *Sandbox
+	sandboxedTemplates map[string]bool // template name -> whether any file of it is sandboxed
+	sandboxMutex       sync.RWMutex
+
+	funcsMutex sync.RWMutex // serializes AddFuncs and ReplaceFuncs, parsing and caching a template holds the read lock
+
+	requestFuncsTemplates map[string]*requestFuncsTemplate // template name -> unexecuted clone, see: ExecuteTemplateWithFuncs
+	requestFuncsMutex     sync.RWMutex
+
+	loader       Loader
+	versions     map[string]map[string]string // template name -> (template file -> version)
+	contextFiles []string                     // context files of the last (re)loading
+	versionMutex sync.RWMutex
 }
 
 type TemplateConfig struct {
-	DirOfRoot                      string           // template root dir
-	DirOfMainRelativeToRoot        string           // template dir: main
-	DirOfContextRelativeToRoot     string           // template dir: context
-	FilePathOfLayoutRelativeToRoot string           // template layout file path
-	Extension                      string           // template extension
-	FuncMap                        template.FuncMap // template functions
-	Delims                         Delims           // delimiters
-
-	IsDebugging          bool // true: Show debug info; false: disable debug info and enable cache.
-	VerboseLevel         int  // 0: not show anything
-	EnableMinifyTemplate bool // enable minify template after loading it and before storing it to the memory.
-	EnableMinifyHtml     bool // decide to minify html while output
-	ShowQps              bool // if VerboseLevel >= 1 || ShowQps { // show qps }, default is false
+	DirOfRoot                      string           `json:"dir_of_root"`                          // template root dir
+	DirsOfOverrideRoot             []string         `json:"dirs_of_override_root"`                // root dirs which override DirOfRoot, the earlier one wins. eg: ["tenants/acme", "themes/dark"]
+	DirOfTenants                   string           `json:"dir_of_tenants"`                       // dir of tenant override roots. eg: "tenants", then tenant "acme" overrides with "tenants/acme"
+	MaxTenants                     int              `json:"max_tenants"`                          // max number of tenants kept in memory, the least recently used is removed. 0: unlimited
+	DirOfMainRelativeToRoot        string           `json:"dir_of_main_relative_to_root"`         // template dir: main
+	DirOfContextRelativeToRoot     string           `json:"dir_of_context_relative_to_root"`      // template dir: context
+	CascadingContextDirName        string           `json:"cascading_context_dir_name"`           // name of context dirs inside main dirs, eg: "_context". "": disabled
+	FilePathOfLayoutRelativeToRoot string           `json:"file_path_of_layout_relative_to_root"` // template layout file path
+	Extension                      string           `json:"extension"`                            // template extension, used if Extensions is empty
+	Extensions                     []string         `json:"extensions"`                           // template extensions. eg: [".tpl.html", ".gohtml", ".tmpl"]
+	IncludePatterns                []string         `json:"include_patterns"`                     // glob patterns of main templates relative to root, empty: all. eg: ["main/blog/**"]
+	ExcludePatterns                []string         `json:"exclude_patterns"`                     // glob patterns of skipped templates relative to root. eg: ["*.bak", "main/admin"]
+	IgnoreFileName                 string           `json:"ignore_file_name"`                     // file(relative to root) of exclude patterns, default: ".tplignore"
+	SkipDrafts                     bool             `json:"skip_drafts"`                          // skip main templates whose file or dir name starts with "_"
+	FuncMap                        template.FuncMap `json:"-"`                                    // template functions
+	Delims                         Delims           `json:"delims"`                               // delimiters
+	MissingKey                     string           `json:"missing_key"`                          // template option missingkey: "default", "zero" or "error"(fail on missing map keys). "": "default"
+
+	IsDebugging          bool   `json:"is_debugging"`           // true: Show debug info; false: disable debug info and enable cache.
+	VerboseLevel         int    `json:"verbose_level"`          // 0: not show anything
+	EnableMinifyTemplate bool   `json:"enable_minify_template"` // enable minify template after loading it and before storing it to the memory.
+	EnableMinifyHtml     bool   `json:"enable_minify_html"`     // decide to minify html while output
+	ShowQps              bool   `json:"show_qps"`               // if VerboseLevel >= 1 || ShowQps { // show qps }, default is false
+	StrictBlocks         bool   `json:"strict_blocks"`          // true: Init fails if a main template misses blocks called by the layout
+	LiveReload           bool   `json:"live_reload"`            // true: inject the live reload script in debug mode, see: LiveReloadHandler
+	LiveReloadPath       string `json:"live_reload_path"`       // path of LiveReloadHandler, default: "/_templatemanager/livereload"
+	EnableRequestFuncs   bool   `json:"enable_request_funcs"`   // true: keep unexecuted clones of templates for ExecuteTemplateWithFuncs
+	DataCheck            string `json:"data_check"`             // debug mode only, check data against fields accessed by templates: "": off, "log", "error"(fail on missing keys), "strict"(fail on missing and unused keys)
 }
 
 type Delims struct {
-	Left  string
-	Right string
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// get returns the left and right delimiters, an empty one is the default "{{" or "}}".
+func (d Delims) get() (left, right string) {
+	left, right = d.Left, d.Right
+	if left == "" {
+		left = "{{"
+	}
+	if right == "" {
+		right = "}}"
+	}
+	return left, right
 }
 
 func New(config TemplateConfig) *TemplateManager {
@@ -118,7 +194,16 @@ func New(config TemplateConfig) *TemplateManager {
 		Config: config,
 
 		TemplatesMap:  make(map[string]*template.Template),
+		dataFields:    make(map[string][]string),
 		templateMutex: sync.RWMutex{},
+
+		aliases:         make(map[string]string),
+		modes:           defaultModes(),
+		stringTemplates: make(map[string]string),
+		versions:        make(map[string]map[string]string),
+		tenants:         make(map[string]*TemplateManager),
+
+		sandboxedTemplates: make(map[string]bool),
 	}
 }
 
@@ -131,6 +216,7 @@ func NewDefaultConfig(isDebugging bool) TemplateConfig {
 		DirOfRoot:                      "templates",
 		DirOfMainRelativeToRoot:        "main",
 		DirOfContextRelativeToRoot:     "context",
+		CascadingContextDirName:        "_context",
 		FilePathOfLayoutRelativeToRoot: "context/layout/layout.tpl.html",
 		Extension:                      ".html",
 		FuncMap:                        make(template.FuncMap),
@@ -164,6 +250,29 @@ func (tm *TemplateManager) GetFilePathOfBase() (name string) {
 	return path.Join(tm.Config.DirOfRoot, tm.Config.FilePathOfLayoutRelativeToRoot)
 }
 
+// GetFilePathOfLayout returns the layout file path of the templateEnv(ContextMode).
+// The nearest layout of cascading context dirs overrides the base one, see: getCascadingLayout.
+func (tm *TemplateManager) GetFilePathOfLayout(te *TemplateEnv) string {
+	if te.Layout != "" {
+		return path.Join(tm.Config.DirOfRoot, te.Layout)
+	}
+	if len(te.Names) > 0 {
+		if layout := tm.getCascadingLayout(te.Names[0]); layout != "" {
+			return layout
+		}
+	}
+	return tm.GetFilePathOfBase()
+}
+
+// getEntryName returns the name of the template to execute.
+func (tm *TemplateManager) getEntryName(te *TemplateEnv) string {
+	mode, ok := tm.GetMode(te.Mode)
+	if !ok {
+		mode = contextMode{}
+	}
+	return mode.EntryName(tm, te)
+}
+
 func (tm *TemplateManager) GetMapOfTemplateNameToDefinedNames() (m map[string]string) {
 	m = make(map[string]string)
 	for k, tpl := range tm.TemplatesMap {
@@ -179,13 +288,41 @@ Report of template manager
 --> config
 %#v
 ------------------------
+--> modes
+`, tm.Config)
+	for _, mode := range tm.GetModes() {
+		s += fmt.Sprintf("%q -> %T\n", mode.Prefix(), mode)
+	}
+	s += fmt.Sprintf(`------------------------
+--> string templates: %q
+`, tm.GetStringTemplateNames())
+	if tm.tenant != "" {
+		s += fmt.Sprintf("--> tenant: %q\n", tm.tenant)
+	} else {
+		s += fmt.Sprintf("--> tenants(least recently used first, max: %d): %q\n", tm.Config.MaxTenants, tm.GetTenants())
+	}
+	s += "------------------------\n--> template files (file -> origin)\n"
+	for _, f := range append(tm.getAllContextFiles(), tm.getMainFiles()...) {
+		s += fmt.Sprintf("%q -> %q\n", f, tm.GetOrigin(f))
+	}
+	s += fmt.Sprintf(`------------------------
 --> (map(sum=%d):  templateName -> it's definedNames), 
-`, tm.Config, len(tm.TemplatesMap))
+`, len(tm.TemplatesMap))
 	i := 0
 	for tplName, definedNames := range tm.GetMapOfTemplateNameToDefinedNames() {
 		i += 1
 		s += fmt.Sprintf("%d: %q -> %s\n", i, tplName, definedNames)
 	}
+	s += "------------------------\n--> data fields (templateName -> fields accessed from the data)\n"
+	dataFields := tm.GetMapOfTemplateNameToDataFields()
+	var tplNames []string
+	for tplName := range dataFields {
+		tplNames = append(tplNames, tplName)
+	}
+	sort.Strings(tplNames)
+	for _, tplName := range tplNames {
+		s += fmt.Sprintf("%q -> %q\n", tplName, dataFields[tplName])
+	}
 	s += "------------------------\n"
 	return s
 }
@@ -198,24 +335,20 @@ func (tm *TemplateManager) getDirOfContext() string {
 	return path.Join(tm.Config.DirOfRoot, tm.Config.DirOfContextRelativeToRoot)
 }
 
-func getTemplateFilePathsByWalking(root string, ext string, prefix string) ([]string, error) {
-	var filePaths []string
-	walkFunc := func(p string, info os.FileInfo, err error) error {
-		if err != nil {
-			currentDir, _ := os.Getwd()
-			log.Panicf("error happens while walking dir: %q(current dir is: %q), walking-root-for-function: %q,  err: %v", p, currentDir, root, err)
-		}
-		if !info.IsDir() && path.Ext(p) == ext {
-			filePaths = append(filePaths, path.Join(prefix, p))
-		}
-		return nil
-	}
-	err := filepath.Walk(root, walkFunc)
+// getTemplateFilePathsByLoader returns the file paths(joined with DirOfRoot) of templates under dir(relative to root).
+func (tm *TemplateManager) getTemplateFilePathsByLoader(dir string) ([]string, error) {
+	names, err := tm.GetLoader().List(dir)
 	if err != nil {
-		log.Printf("Faild walking root dir: %q. err: %q", root, err)
-		log.Fatalf("Faild walking root dir: %q. err: %q", root, err)
+		log.Printf("Failed listing templates of dir: %q. err: %q", dir, err)
 		return nil, err
 	}
+	ignorePatterns := tm.getIgnorePatterns()
+	var filePaths []string
+	for _, name := range names {
+		if tm.isTemplateFile(name, ignorePatterns) {
+			filePaths = append(filePaths, path.Join(tm.Config.DirOfRoot, name))
+		}
+	}
 	return filePaths, nil
 }
 
@@ -229,14 +362,24 @@ func ContainsString(slice []string, contains string) bool {
 	return false
 }
 
+// GetContextFiles returns the context files(include the layout file).
+func (tm *TemplateManager) GetContextFiles() []string {
+	return tm.getContextFiles()
+}
+
 func (tm *TemplateManager) getContextFiles() []string {
-	contextFiles, err := getTemplateFilePathsByWalking(tm.getDirOfContext(), tm.Config.Extension, "")
+	contextFiles, err := tm.getTemplateFilePathsByLoader(tm.Config.DirOfContextRelativeToRoot)
 	if err != nil {
 		log.Fatalf("Could not get context files of dir: %q. err: %s", tm.getDirOfContext(), err)
 	}
 	if tm.DoShowDebugMessage() {
 		log.Printf("ContextFiles are: %v", contextFiles)
 	}
+	for _, f := range tm.getStringTemplateFilePaths(tm.getDirOfContext()) {
+		if !ContainsString(contextFiles, f) {
+			contextFiles = append(contextFiles, f)
+		}
+	}
 	if !ContainsString(contextFiles, tm.GetFilePathOfBase()) {
 		contextFiles = append(contextFiles, tm.GetFilePathOfBase())
 	}
@@ -247,7 +390,7 @@ func (tm *TemplateManager) getContextFiles() []string {
 // get templates which is not context file.
 func (tm *TemplateManager) getMainFiles() []string {
 	// mainFiles, err := filepath.Glob(path.Join(tm.getDirOfMain(), "**", "*"+tm.Config.Extension))
-	mainFiles, err := getTemplateFilePathsByWalking(tm.getDirOfMain(), tm.Config.Extension, "")
+	mainFiles, err := tm.getTemplateFilePathsByLoader(tm.Config.DirOfMainRelativeToRoot)
 	if err != nil {
 		log.Fatalf("Could not get main files of dir: %q. err: %s", tm.getDirOfMain(), err)
 	}
@@ -255,8 +398,8 @@ func (tm *TemplateManager) getMainFiles() []string {
 	// DirOfContextRelativeToRoot might be a sub directory of DirOfMainRelativeToRoot
 	var mf []string
 	for _, f := range mainFiles {
-		// skip context files (if context_dir is a sub_dir of main_dir)
-		if strings.HasPrefix(f, tm.Config.DirOfContextRelativeToRoot) {
+		// skip context files (if context_dir is a sub_dir of main_dir) and files not included
+		if !tm.isMainFile(tm.getBasicTemplateNameByFilePath(f)) {
 			continue
 		}
 		mf = append(mf, f)
@@ -282,9 +425,30 @@ func (tm *TemplateManager) setTemplate(te *TemplateEnv, tpl *template.Template)
 	}
 
 	tplName := te.StandardTemplateName()
+	tm.setRequestFuncsTemplate(tplName, tpl)
+	// the template is not executed yet, so its parse trees could be walked safely.
+	fields := getDataFields(tpl, tm.getEntryName(te))
 	tm.templateMutex.Lock()
 	defer tm.templateMutex.Unlock()
 	tm.TemplatesMap[tplName] = tpl
+	tm.dataFields[tplName] = fields
+}
+
+// setTemplatesWithFuncs replaces FuncMap and the templates(standard template name -> template) at once, see: AddFuncs.
+func (tm *TemplateManager) setTemplatesWithFuncs(templates map[string]*template.Template, funcMap template.FuncMap) {
+	fieldsOfTemplates := make(map[string][]string, len(templates))
+	for tplName, tpl := range templates {
+		tm.setRequestFuncsTemplate(tplName, tpl)
+		te := newTemplateEnvByParsing(tplName, tm.getModePrefixes()...)
+		fieldsOfTemplates[tplName] = getDataFields(tpl, tm.getEntryName(te))
+	}
+	tm.templateMutex.Lock()
+	defer tm.templateMutex.Unlock()
+	tm.Config.FuncMap = funcMap
+	for tplName, tpl := range templates {
+		tm.TemplatesMap[tplName] = tpl
+		tm.dataFields[tplName] = fieldsOfTemplates[tplName]
+	}
 }
 
 func (tm *TemplateManager) parseMainFiles() error {
@@ -293,6 +457,13 @@ func (tm *TemplateManager) parseMainFiles() error {
 			log.Printf("\n")
 			log.Printf("--(template: seq: %d)--> Parsing template file: %q", i, f)
 		}
+		if tm.IsSandboxed(tm.getBasicTemplateNameByFilePath(f)) {
+			// a broken user-authored template should not break Init.
+			if err := tm.tryParseMainTemplateByFilePath(f); err != nil {
+				log.Printf("Skipped sandboxed template file: %q. err: %s", f, err)
+			}
+			continue
+		}
 		tm.parseMainTemplateByFilePath(f)
 	}
 	log.Printf("")
@@ -300,57 +471,89 @@ func (tm *TemplateManager) parseMainFiles() error {
 }
 
 func (tm *TemplateManager) MustTemplate(tplName string, filesForParsing []string) *template.Template {
-	if !tm.Config.EnableMinifyTemplate {
-		tpl := template.Must(template.New(tplName).Funcs(tm.Config.FuncMap).ParseFiles(filesForParsing...))
-		return tpl
-	} else {
-		/* Could not find a better way to store minified content while keeps filenames which required by template to "ParseFiles"
-		 * solution:
-		 * 	create a tmp dir, then populates it with minified files.
-		 * ...
-		 * ...
-		 */
-		if tm.DoShowDebugMessage() {
-			log.Printf("Minifying template: %q", tplName)
+	if len(filesForParsing) == 0 {
+		panic(fmt.Sprintf("no files for parsing template: %q", tplName))
+	}
+	tm.recordTemplateFiles(tplName, filesForParsing)
+	return tm.parseTemplateFiles(tplName, filesForParsing, tm.getFuncMap())
+}
+
+// mustCacheTemplate parses and caches the template. AddFuncs waits for it, so it could not be cached with a replaced FuncMap.
+func (tm *TemplateManager) mustCacheTemplate(te *TemplateEnv, filesForParsing []string) *template.Template {
+	tm.funcsMutex.RLock()
+	defer tm.funcsMutex.RUnlock()
+	tpl := tm.MustTemplate(te.StandardTemplateName(), filesForParsing)
+	tm.setTemplate(te, tpl)
+	return tpl
+}
+
+// recordTemplateFiles records versions and sandboxed marks of the files of the template.
+func (tm *TemplateManager) recordTemplateFiles(tplName string, filesForParsing []string) {
+	tm.recordVersions(tplName, filesForParsing)
+	if tm.getSandbox() != nil {
+		tm.markSandboxedTemplate(tplName, filesForParsing)
+	}
+}
+
+// getFuncMap returns FuncMap, which is replaced by AddFuncs under templateMutex.
+func (tm *TemplateManager) getFuncMap() template.FuncMap {
+	tm.templateMutex.RLock()
+	defer tm.templateMutex.RUnlock()
+	return tm.Config.FuncMap
+}
+
+// parseTemplateFiles parses the files with funcMap, it panics on errors.
+// Unlike MustTemplate, versions and sandboxed marks of the template are not recorded.
+func (tm *TemplateManager) parseTemplateFiles(tplName string, filesForParsing []string, funcMap template.FuncMap) *template.Template {
+	if len(filesForParsing) == 0 {
+		panic(fmt.Sprintf("no files for parsing template: %q", tplName))
+	}
+	if tm.Config.EnableMinifyTemplate && tm.DoShowDebugMessage() {
+		log.Printf("Minifying template: %q", tplName)
+	}
+
+	// Same as ParseFiles: every file is parsed as a template named by its base name.
+	tpl := template.New(tplName).Delims(tm.Config.Delims.get()).Funcs(funcMap)
+	if tm.Config.MissingKey != "" {
+		tpl.Option("missingkey=" + tm.Config.MissingKey)
+	}
+	if tm.getSandbox() != nil {
+		if err := tm.validateSandboxedFiles(filesForParsing); err != nil {
+			log.Printf("%s", err)
+			panic(err)
 		}
-		tmpDir, err := ioutil.TempDir("", "go-template")
+	}
+	for _, f := range filesForParsing {
+		b, err := tm.readTemplateFile(f)
 		if err != nil {
-			log.Printf("Could not create temparary dir. err: %s", err)
+			log.Printf("Could not read template file: %q. err: %s", f, err)
 			panic(err)
-			return nil
 		}
-		defer func() {
-			err := os.RemoveAll(tmpDir)
-			if err != nil {
-				log.Printf("could not remove tmpDir: %q. e: %v", tmpDir, err)
-			}
-		}()
-
-		for _, f := range filesForParsing {
-			baseName := path.Base(f)
-			fpath := path.Join(tmpDir, baseName)
-			outFile, err := os.Create(fpath)
-			if err != nil {
-				fmt.Printf("Could not create file: %q. err: %s", fpath, err)
-				panic(err)
-				return nil
-			}
-
-			inFile, err := os.Open(f)
-			if err != nil {
-				fmt.Printf("Could not open file: %q. err: %s", f, err)
-				panic(err)
-				return nil
-			}
-			err = goTemplateMinifier.Minify(MimeHtml, outFile, inFile)
+		if tm.Config.EnableMinifyTemplate {
+			buf := new(bytes.Buffer)
+			err = goTemplateMinifier.Minify(MimeHtml, buf, bytes.NewReader(b))
 			if err != nil {
 				log.Printf("Could not minify template in buf. err: %s", err)
 				panic(err)
 			}
+			b = buf.Bytes()
 		}
-		tpl := template.Must(template.New(tplName).Funcs(tm.Config.FuncMap).ParseGlob(filepath.Join(tmpDir, "*")))
-		return tpl
+		template.Must(tpl.New(path.Base(f)).Parse(string(b)))
 	}
+	return tpl
+}
+
+// readTemplateFile reads the content of a template file. String templates take precedence over the loader.
+func (tm *TemplateManager) readTemplateFile(filePath string) ([]byte, error) {
+	if src, ok := tm.getStringTemplate(filePath); ok {
+		return []byte(src), nil
+	}
+	r, err := tm.GetLoader().Open(tm.getBasicTemplateNameByFilePath(filePath))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
 }
 
 func (tm *TemplateManager) ParseContextModeTemplate(te *TemplateEnv) *template.Template {
@@ -368,12 +571,10 @@ func (tm *TemplateManager) ParseContextModeTemplate(te *TemplateEnv) *template.T
 			log.Printf("ContextEnv Parsing: (tplName -> tplPaths) (%q -> %q)", tplName, filePaths)
 		}
 	}
-	contextFiles := tm.getContextFiles()
-	filesForParsing := append(contextFiles, filePaths...)
+	filesForParsing := tm.getFilesForParsing(te)
 
 	// tpl := template.Must(template.New(tplName).Funcs(tm.Config.FuncMap).ParseFiles(filesForParsing...))
-	tpl := tm.MustTemplate(tplName, filesForParsing)
-	tm.setTemplate(te, tpl)
+	tpl := tm.mustCacheTemplate(te, filesForParsing)
 	if tm.DoShowDebugMessage() {
 		log.Printf("ContextEnv template:     (templateName -> definedTemplates): %q -> %s", tpl.Name(), tpl.DefinedTemplates())
 	}
@@ -385,19 +586,41 @@ func (tm *TemplateManager) ParseFilesModeTemplate(te *TemplateEnv) *template.Tem
 		return nil
 	}
 	tplName := te.StandardTemplateName()
-	filesForParsing := te.GetFilePaths(tm.Config.DirOfRoot)
+	filesForParsing := tm.getFilesForParsing(te)
 	if tm.DoShowDebugMessage() {
 		log.Printf("FilesEnv Parsing: (tplName -> tplPath) (%q -> %q)", tplName, filesForParsing)
 	}
 	// tpl := template.Must(template.New(tplName).Funcs(tm.Config.FuncMap).ParseFiles(filesForParsing...))
-	tpl := tm.MustTemplate(tplName, filesForParsing)
-	tm.setTemplate(te, tpl)
+	tpl := tm.mustCacheTemplate(te, filesForParsing)
 	if tm.DoShowDebugMessage() {
 		log.Printf("FilesEnv template: (tplName -> definedTemplates): %q -> %s", tpl.Name(), tpl.DefinedTemplates())
 	}
 	return tpl
 }
 
+// getFilesForParsing returns the files to parse for the templateEnv.
+func (tm *TemplateManager) getFilesForParsing(te *TemplateEnv) []string {
+	mode, ok := tm.GetMode(te.Mode)
+	if !ok {
+		mode = contextMode{}
+	}
+	return mode.FilesForParsing(tm, te)
+}
+
+// parseModeTemplate parses the templateEnv of a registered mode other than ContextMode and FilesMode.
+func (tm *TemplateManager) parseModeTemplate(mode Mode, te *TemplateEnv) *template.Template {
+	tplName := te.StandardTemplateName()
+	filesForParsing := mode.FilesForParsing(tm, te)
+	if tm.DoShowDebugMessage() {
+		log.Printf("%T Parsing: (tplName -> tplPaths) (%q -> %q)", mode, tplName, filesForParsing)
+	}
+	tpl := tm.mustCacheTemplate(te, filesForParsing)
+	if tm.DoShowDebugMessage() {
+		log.Printf("%T template: (tplName -> definedTemplates): %q -> %s", mode, tpl.Name(), tpl.DefinedTemplates())
+	}
+	return tpl
+}
+
 func (tm *TemplateManager) parseTemplate(te *TemplateEnv) *template.Template {
 	tplName := te.StandardTemplateName()
 	if te.IsContextMode() {
@@ -410,6 +633,11 @@ func (tm *TemplateManager) parseTemplate(te *TemplateEnv) *template.Template {
 			log.Printf("tplName: %q is a filesEnv tplName", tplName)
 		}
 		return tm.ParseFilesModeTemplate(te)
+	} else if mode, ok := tm.GetMode(te.Mode); ok {
+		if tm.DoShowDebugMessage() {
+			log.Printf("tplName: %q is a %T tplName", tplName, mode)
+		}
+		return tm.parseModeTemplate(mode, te)
 	} else {
 		log.Printf("tplName: %q is an invalid tplName", tplName)
 		msg := fmt.Sprintf("Could not find template by tplName: %q", tplName)
@@ -437,14 +665,49 @@ func (tm *TemplateManager) parseMainTemplateByFilePath(filePath string) *templat
 
 func (tm *TemplateManager) Init(useMaster bool) error {
 	log.Printf("Initing templates. DirOfMainRelativeToRoot: %q, DirOfContextRelativeToRoot: %q", tm.Config.DirOfMainRelativeToRoot, tm.Config.DirOfContextRelativeToRoot)
+	if err := tm.Prepare(); err != nil {
+		log.Printf("TemplateManager init error: %s", err)
+		return err
+	}
+	if err := tm.parseMainFiles(); err != nil {
+		return err
+	}
+	if err := tm.checkBlocks(); err != nil {
+		log.Printf("TemplateManager init error: %s", err)
+		return err
+	}
+	return nil
+}
+
+// Prepare validates the config and prepares the manager like Init, but templates are parsed lazily(on first use).
+// eg: render one template of a big tree.
+func (tm *TemplateManager) Prepare() error {
+	if err := tm.Validate(); err != nil {
+		return err
+	}
+	tm.prepare()
+	return nil
+}
+
+// prepare adds the "include" function and indexes templates, templates are parsed lazily after it.
+func (tm *TemplateManager) prepare() {
 	includeFunc := func(name string, data interface{}) (template.HTML, error) {
 		buf := new(bytes.Buffer)
 		err := tm.ExecuteTemplate(buf, name, data)
 		return template.HTML(buf.String()), err
 	}
+	tm.templateMutex.Lock()
 	tm.Config.FuncMap["include"] = includeFunc
+	tm.templateMutex.Unlock()
 
-	return tm.parseMainFiles()
+	tm.resolveMutex.Lock()
+	tm.nameIndex = tm.buildNameIndex()
+	tm.cascadingIndex = tm.buildCascadingIndex()
+	tm.resolveMutex.Unlock()
+	contextFiles := tm.getAllContextFiles()
+	tm.versionMutex.Lock()
+	tm.contextFiles = contextFiles
+	tm.versionMutex.Unlock()
 }
 
 func (tm *TemplateManager) GetTemplate(tplName string) (*template.Template, bool) {
@@ -467,23 +730,45 @@ func (tm *TemplateManager) rightBeforeExecuteTemplate(tpl *template.Template, ou
 			return err
 		}
 
-		if err := htmlMinifier.Minify(MimeHtml, out, buf); err != nil {
-			log.Printf("Error while minifying text/html. err: %s", err)
-			return err
-		}
 		//buf.WriteTo(out)
-		return nil
+		return minifyHtml(out, buf)
 		//return tm.ExecuteTemplate(out, name, data)
 	}
 }
 
+// minifyHtml minifies the html of r to out.
+func minifyHtml(out io.Writer, r io.Reader) error {
+	if err := htmlMinifier.Minify(MimeHtml, out, r); err != nil {
+		log.Printf("Error while minifying text/html. err: %s", err)
+		return err
+	}
+	return nil
+}
+
 func (tm *TemplateManager) ExecuteTemplate(out io.Writer, templateName string, data interface{}) error {
+	return tm.executeTemplate(out, templateName, data, nil)
+}
+
+// executeTemplate executes the template, funcs override functions of FuncMap if not empty, see: ExecuteTemplateWithFuncs.
+func (tm *TemplateManager) executeTemplate(out io.Writer, templateName string, data interface{}, funcs template.FuncMap) error {
 	t0 := time.Now()
 	var tpl *template.Template
-	var err error
 	var ok bool
 
-	te := NewTemplateEnvByParsing(templateName)
+	if tenant := tm.getTenantOfTemplateName(templateName); tenant != "" && tm.tenant == "" {
+		tenantManager, err := tm.GetTenantManager(tenant)
+		if err != nil {
+			log.Printf("TemplateManager get tenant error: %s", err)
+			return err
+		}
+		return tenantManager.executeTemplate(out, templateName, data, funcs)
+	}
+
+	te, err := tm.NewTemplateEnv(templateName)
+	if err != nil {
+		log.Printf("TemplateManager resolve template name error: %s", err)
+		return err
+	}
 	tplName := te.StandardTemplateName()
 	if tm.DoShowDebugMessage() {
 		log.Printf("Request executing template name: %q, standard template name is: %q", templateName, tplName)
@@ -492,21 +777,51 @@ func (tm *TemplateManager) ExecuteTemplate(out io.Writer, templateName string, d
 
 	if !ok || tm.Config.IsDebugging {
 		log.Printf("Template-not-exist or in-debug-mode. Requst executing templateName: %q. Re-parsing it.", tplName)
-		tpl = tm.parseTemplate(te)
+		if tm.getSandbox() != nil {
+			// errors of user-authored templates are returned instead of panicking.
+			if _, err := tm.tryParseTemplate(te); err != nil {
+				log.Printf("TemplateManager parse template error: %s", err)
+				return err
+			}
+		} else {
+			tpl = tm.parseTemplate(te)
+		}
 		tpl, ok = tm.GetTemplate(tplName)
 		if !ok {
 			log.Printf("Could not find correspondent template by tplName: %s", tplName)
 		}
 	}
 
-	var name string
-	if te.IsContextMode() {
-		name = filepath.Base(tm.Config.FilePathOfLayoutRelativeToRoot)
-	} else if te.IsFilesMode() {
-		name = filepath.Base(te.Names[0])
+	name := tm.getEntryName(te)
+	if err := tm.checkData(tplName, data); err != nil {
+		log.Printf("TemplateManager check data error: %s", err)
+		return err
+	}
+	if len(funcs) > 0 {
+		clone, release, err := tm.getRequestFuncsTemplate(tplName, tpl, funcs)
+		if err != nil {
+			log.Printf("TemplateManager execute template with funcs error: %s", err)
+			return err
+		}
+		tpl = clone
+		// a sandboxed execution might outlive its timeout, then the clone is not reused.
+		if !tm.isSandboxedTemplate(tplName) {
+			defer release()
+		}
 	}
 
-	err = tm.rightBeforeExecuteTemplate(tpl, out, name, data)
+	if tm.isSandboxedTemplate(tplName) {
+		err = tm.executeSandboxed(func(w io.Writer) error {
+			return tm.rightBeforeExecuteTemplate(tpl, w, name, data)
+		}, out)
+	} else if tm.isLiveReloading() {
+		// the script is injected before minifying, the minifier might drop "</body>".
+		err = tm.executeWithLiveReload(func(w io.Writer) error {
+			return tpl.ExecuteTemplate(w, name, data)
+		}, out)
+	} else {
+		err = tm.rightBeforeExecuteTemplate(tpl, out, name, data)
+	}
 	if err != nil {
 		log.Printf("TemplateManager execute template error: %s", err)
 		return err
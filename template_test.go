@@ -3,6 +3,8 @@ package templatemanager
 import (
 	"bytes"
 	"log"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 )
@@ -76,6 +78,54 @@ func TestTemplateManager_ExecuteTemplate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "ContextEnv: render a file by short name",
+			args: args{
+				templateName: "demo/demo1",
+				data:         data,
+			},
+			wantErr: false,
+		},
+		{
+			name: "FilesMode: render multiple template by short names",
+			args: args{
+				templateName: "F->demo/demo2;main/demo/demo1.tpl",
+				data:         data,
+			},
+			wantErr: false,
+		},
+		{
+			name: "FilesMode: render the second file as entry",
+			args: args{
+				templateName: "F->demo/demo2;demo/demo1@demo/demo1",
+				data:         data,
+			},
+			wantErr: false,
+		},
+		{
+			name: "ContextEnv: render a defined template as entry with another layout",
+			args: args{
+				templateName: "demo/demo1@content?layout=context/layout/simple.tpl.html",
+				data:         data,
+			},
+			wantErr: false,
+		},
+		{
+			name: "ContextEnv: render a non-existent template",
+			args: args{
+				templateName: "demo/not-exist",
+				data:         data,
+			},
+			wantErr: true,
+		},
+		{
+			name: "ContextEnv: render with a non-existent layout",
+			args: args{
+				templateName: "demo/demo1?layout=context/layout/not-exist.tpl.html",
+				data:         data,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -86,6 +136,9 @@ func TestTemplateManager_ExecuteTemplate(t *testing.T) {
 				t.Errorf("ExecuteTemplate() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			if tt.wantErr {
+				return
+			}
 
 			gotStr := out.String()
 			if len(gotStr) < 10 {
@@ -97,6 +150,64 @@ func TestTemplateManager_ExecuteTemplate(t *testing.T) {
 	}
 }
 
+func TestTemplateManager_Resolve(t *testing.T) {
+	tests := []struct {
+		name         string
+		templateName string
+		want         string
+		wantErr      bool
+	}{
+		{"canonical name", "main/demo/demo1.tpl.html", "C->main/demo/demo1.tpl.html", false},
+		{"without extension", "C->main/demo/demo1.tpl", "C->main/demo/demo1.tpl.html", false},
+		{"without main dir and extensions", "demo/dir1/dir2/any", "C->main/demo/dir1/dir2/any.tpl.html", false},
+		{"FilesMode", "F-> demo/demo2; demo/demo1", "F->main/demo/demo2.tpl.html;main/demo/demo1.tpl.html", false},
+		{"non-existent", "demo/demo3", "", true},
+		{"layout", "demo/demo1?layout=/context/layout/simple.tpl.html", "C->main/demo/demo1.tpl.html?layout=context/layout/simple.tpl.html", false},
+		{"non-existent layout", "demo/demo1?layout=context/layout/none.tpl.html", "", true},
+		{"layout outside root", "demo/demo1?layout=../templates/context/layout/simple.tpl.html", "", true},
+		{"tenant", "demo/demo1?tenant=acme", "C->main/demo/demo1.tpl.html?tenant=acme", false},
+		{"unknown option", "demo/demo1?v=1", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, files, err := gTplMgr.Resolve(tt.templateName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() got = %q, want %q", got, tt.want)
+			}
+			if !tt.wantErr && len(files) == 0 {
+				t.Errorf("Resolve() got no files")
+			}
+		})
+	}
+}
+
+func TestTemplateManager_ResolveAmbiguous(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"main/demo/demo1.tpl.html", "main/demo/demo1.html", "context/layout/layout.tpl.html"} {
+		p := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(`{{ define "content" }}demo{{ end }}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conf := NewDefaultConfig(false)
+	conf.DirOfRoot = root
+	tm := New(conf)
+
+	if _, _, err := tm.Resolve("demo/demo1"); err == nil {
+		t.Errorf("Resolve() expects an ambiguity error")
+	}
+	if got, _, err := tm.Resolve("demo/demo1.tpl"); err != nil || got != "C->main/demo/demo1.tpl.html" {
+		t.Errorf("Resolve() got = %q, err = %v", got, err)
+	}
+}
+
 func BenchmarkTemplateManager_ExecuteTemplate(b *testing.B) {
 	gTplMgr.Config.IsDebugging = false
 	gTplMgr.SetVerboseLevel(0)
@@ -0,0 +1,100 @@
+package templatemanagertest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffContextLines is the number of unchanged lines shown around changed lines of Diff.
+var DiffContextLines = 2
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+	line int // line number of want(' ', '-') or got('+')
+}
+
+// Diff returns the line diff of want and got, eg:
+//
+//	--- want
+//	+++ got
+//	@@ want line 2 @@
+//	  <ul>
+//	- <li>a</li>
+//	+ <li>b</li>
+//	  </ul>
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	lines := diffLines(strings.Split(want, "\n"), strings.Split(got, "\n"))
+
+	// only the changed lines and DiffContextLines around them are shown.
+	show := make([]bool, len(lines))
+	for i, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for j := i - DiffContextLines; j <= i+DiffContextLines; j++ {
+			if j >= 0 && j < len(lines) {
+				show[j] = true
+			}
+		}
+	}
+	b := &strings.Builder{}
+	b.WriteString("--- want\n+++ got\n")
+	for i, l := range lines {
+		if !show[i] {
+			continue
+		}
+		if i == 0 || !show[i-1] {
+			wantLine := 1
+			for j := i; j < len(lines); j++ {
+				if lines[j].op != '+' {
+					wantLine = lines[j].line
+					break
+				}
+			}
+			fmt.Fprintf(b, "@@ want line %d @@\n", wantLine)
+		}
+		fmt.Fprintf(b, "%c %s\n", l.op, l.text)
+	}
+	return b.String()
+}
+
+// diffLines returns the shortest edit of a to b, by the longest common subsequence.
+func diffLines(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i + 1})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i], i + 1})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], j + 1})
+			j++
+		}
+	}
+	return lines
+}
@@ -0,0 +1,133 @@
+package templatemanagertest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/darkdarkfruit/templatemanager"
+	"golang.org/x/net/html"
+)
+
+// Document is the parsed html output of a template, it asserts by css selectors:
+//
+//	doc := templatemanagertest.RenderHTML(t, tm, "home", data)
+//	doc.AssertExists("nav > a.active")
+//	doc.AssertText("h1", "hi alice")
+//	doc.AssertAttr("a.logo", "href", "/")
+//	doc.AssertCount("ul.items > li", 3)
+type Document struct {
+	Root *html.Node
+	t    testing.TB
+}
+
+// ParseHTML parses s into a Document, the test fails if s could not be parsed.
+func ParseHTML(t testing.TB, s string) *Document {
+	t.Helper()
+	root, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("could not parse html: %s", err)
+		return nil
+	}
+	return &Document{Root: root, t: t}
+}
+
+// RenderHTML renders the template with data and parses the output, see: Render.
+func RenderHTML(t testing.TB, tm *templatemanager.TemplateManager, name string, data interface{}) *Document {
+	t.Helper()
+	got, ok := render(t, tm, name, data)
+	if !ok {
+		return &Document{Root: &html.Node{Type: html.DocumentNode}, t: t}
+	}
+	return ParseHTML(t, got)
+}
+
+// Find returns the elements matching the css selector, the test fails if the selector is invalid.
+func (d *Document) Find(selector string) []*html.Node {
+	d.t.Helper()
+	sel, err := cascadia.Parse(selector)
+	if err != nil {
+		d.t.Fatalf("invalid css selector %q: %s", selector, err)
+		return nil
+	}
+	return cascadia.QueryAll(d.Root, sel)
+}
+
+// findFirst returns the first element matching the selector, the test fails if there is none.
+func (d *Document) findFirst(selector string) *html.Node {
+	d.t.Helper()
+	nodes := d.Find(selector)
+	if len(nodes) == 0 {
+		d.t.Errorf("no element matches %q", selector)
+		return nil
+	}
+	return nodes[0]
+}
+
+func (d *Document) AssertExists(selector string) {
+	d.t.Helper()
+	d.findFirst(selector)
+}
+
+func (d *Document) AssertNotExists(selector string) {
+	d.t.Helper()
+	if n := len(d.Find(selector)); n > 0 {
+		d.t.Errorf("%d elements match %q, want none", n, selector)
+	}
+}
+
+func (d *Document) AssertCount(selector string, want int) {
+	d.t.Helper()
+	if n := len(d.Find(selector)); n != want {
+		d.t.Errorf("%d elements match %q, want %d", n, selector, want)
+	}
+}
+
+// AssertText compares the text(whitespaces collapsed, see: Text) of the first element matching the selector.
+func (d *Document) AssertText(selector string, want string) {
+	d.t.Helper()
+	if n := d.findFirst(selector); n != nil {
+		if got := Text(n); got != want {
+			d.t.Errorf("text of %q = %q, want %q", selector, got, want)
+		}
+	}
+}
+
+// AssertAttr compares the attribute of the first element matching the selector.
+func (d *Document) AssertAttr(selector string, key string, want string) {
+	d.t.Helper()
+	if n := d.findFirst(selector); n != nil {
+		got, ok := Attr(n, key)
+		if !ok {
+			d.t.Errorf("attribute %q of %q does not exist, want %q", key, selector, want)
+		} else if got != want {
+			d.t.Errorf("attribute %q of %q = %q, want %q", key, selector, got, want)
+		}
+	}
+}
+
+// Text returns the text content of the node, whitespaces are collapsed and trimmed. eg: "hi alice"
+func Text(n *html.Node) string {
+	b := &strings.Builder{}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// Attr returns the attribute of the node, false if it does not exist.
+func Attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key && a.Namespace == "" {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,44 @@
+package templatemanagertest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	tm := NewManager(t, map[string]string{
+		"context/layout/layout.tpl.html": `<html><body><nav><a class="logo" href="/">home</a></nav>{{ template "content" . }}</body></html>`,
+		"main/list.tpl.html": `{{ define "content" }}<h1>
+	hi   {{ .name }}
+</h1><ul class="items">{{ range .items }}<li>{{ . }}</li>{{ end }}</ul>{{ end }}`,
+	})
+	doc := RenderHTML(t, tm, "list", map[string]interface{}{"name": "alice", "items": []string{"a", "b", "c"}})
+	doc.AssertExists("nav > a.logo")
+	doc.AssertNotExists("footer")
+	doc.AssertCount("ul.items > li", 3)
+	doc.AssertText("h1", "hi alice")
+	doc.AssertText("ul.items", "abc")
+	doc.AssertAttr("a.logo", "href", "/")
+
+	tests := []struct {
+		name   string
+		assert func(d *Document)
+		want   string
+	}{
+		{"AssertExists", func(d *Document) { d.AssertExists("footer") }, `no element matches "footer"`},
+		{"AssertNotExists", func(d *Document) { d.AssertNotExists("li") }, `3 elements match "li", want none`},
+		{"AssertCount", func(d *Document) { d.AssertCount("li", 2) }, `3 elements match "li", want 2`},
+		{"AssertText", func(d *Document) { d.AssertText("h1", "hi bob") }, `text of "h1" = "hi alice", want "hi bob"`},
+		{"AssertText of missing element", func(d *Document) { d.AssertText("h2", "") }, `no element matches "h2"`},
+		{"AssertAttr", func(d *Document) { d.AssertAttr("a.logo", "href", "/home") }, `attribute "href" of "a.logo" = "/", want "/home"`},
+		{"AssertAttr of missing attribute", func(d *Document) { d.AssertAttr("a.logo", "title", "") }, `attribute "title" of "a.logo" does not exist`},
+		{"invalid selector", func(d *Document) { d.Find("a[") }, `invalid css selector "a["`},
+	}
+	for _, tt := range tests {
+		f := &fakeTB{}
+		tt.assert(&Document{Root: doc.Root, t: f})
+		if len(f.messages) != 1 || !strings.Contains(f.messages[0], tt.want) {
+			t.Errorf("%s: messages = %q, want %q", tt.name, f.messages, tt.want)
+		}
+	}
+}
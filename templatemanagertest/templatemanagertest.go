@@ -0,0 +1,224 @@
+// Package templatemanagertest provides helpers of golden-file(snapshot) testing for templates.
+//
+//	func TestHome(t *testing.T) {
+//		tm := templatemanagertest.NewManager(t, map[string]string{
+//			"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`,
+//			"main/home.tpl.html":             `{{ define "content" }}hi {{ .name }}{{ end }}`,
+//		})
+//		templatemanagertest.AssertRenderGolden(t, tm, "home", map[string]string{"name": "alice"}, "home.html")
+//	}
+//
+// Golden files are under "testdata/", run "go test -templatemanagertest.update" to create or update them.
+package templatemanagertest
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/darkdarkfruit/templatemanager"
+	"gopkg.in/yaml.v3"
+)
+
+// UpdateFlagName is the flag of updating golden files, eg: go test ./... -templatemanagertest.update
+// It is prefixed by the package name, so it does not collide with an "-update" flag of the test package.
+const UpdateFlagName = "templatemanagertest.update"
+
+// DefaultGoldenDir is the dir of golden files, relative to the dir of the test.
+var DefaultGoldenDir = "testdata"
+
+var update = flag.Bool(UpdateFlagName, false, "update golden files of templatemanagertest")
+
+func isUpdating() bool {
+	return *update
+}
+
+// Options of comparing output with golden files.
+type Options struct {
+	GoldenDir        string // dir of golden files, default: DefaultGoldenDir
+	TrimSpace        bool   // true: trim leading and trailing spaces of the output
+	TrimLines        bool   // true: trim leading and trailing spaces of every line
+	IgnoreBlankLines bool   // true: remove blank lines
+	CollapseSpace    bool   // true: replace every run of spaces(including newlines) with a space
+}
+
+var spaceRegexp = regexp.MustCompile(`\s+`)
+
+// Normalize normalizes whitespaces of s by options.
+func Normalize(s string, options Options) string {
+	s = strings.Replace(s, "\r\n", "\n", -1)
+	if options.TrimLines || options.IgnoreBlankLines {
+		var lines []string
+		for _, line := range strings.Split(s, "\n") {
+			if options.TrimLines {
+				line = strings.TrimSpace(line)
+			}
+			if options.IgnoreBlankLines && strings.TrimSpace(line) == "" {
+				continue
+			}
+			lines = append(lines, line)
+		}
+		s = strings.Join(lines, "\n")
+	}
+	if options.CollapseSpace {
+		s = spaceRegexp.ReplaceAllString(s, " ")
+	}
+	if options.TrimSpace || options.CollapseSpace {
+		s = strings.TrimSpace(s)
+	}
+	return s
+}
+
+func getOptions(options []Options) Options {
+	var o Options
+	if len(options) > 0 {
+		o = options[0]
+	}
+	if o.GoldenDir == "" {
+		o.GoldenDir = DefaultGoldenDir
+	}
+	return o
+}
+
+// WriteFiles writes files(path relative to root: content) to a temp dir and returns it as the root.
+func WriteFiles(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root, err := ioutil.TempDir("", "templatemanagertest")
+	if err != nil {
+		t.Fatalf("could not create temp dir: %s", err)
+		return ""
+	}
+	if c, ok := t.(interface{ Cleanup(func()) }); ok {
+		c.Cleanup(func() { os.RemoveAll(root) })
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("could not create dir of %q: %s", name, err)
+			return ""
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write %q: %s", name, err)
+			return ""
+		}
+	}
+	return root
+}
+
+// NewManager returns an initialized manager(production mode) which loads templates from files(in memory),
+// files are paths relative to root: content. configure modifies the default config before Init.
+func NewManager(t testing.TB, files map[string]string, configure ...func(conf *templatemanager.TemplateConfig)) *templatemanager.TemplateManager {
+	t.Helper()
+	conf := templatemanager.NewDefaultConfig(false)
+	conf.DirOfRoot = "memory"
+	for _, f := range configure {
+		f(&conf)
+	}
+	tm := templatemanager.New(conf)
+	tm.SetLoader(templatemanager.NewMemoryLoader(files))
+	if err := tm.Init(true); err != nil {
+		t.Fatalf("could not init template manager: %s", err)
+		return nil
+	}
+	return tm
+}
+
+// NewManagerFromDir returns an initialized manager(production mode) which loads templates from the dir root.
+func NewManagerFromDir(t testing.TB, root string, configure ...func(conf *templatemanager.TemplateConfig)) *templatemanager.TemplateManager {
+	t.Helper()
+	conf := templatemanager.NewDefaultConfig(false)
+	conf.DirOfRoot = root
+	for _, f := range configure {
+		f(&conf)
+	}
+	tm := templatemanager.New(conf)
+	if err := tm.Init(true); err != nil {
+		t.Fatalf("could not init template manager: %s", err)
+		return nil
+	}
+	return tm
+}
+
+// LoadFixture reads data from a json or yaml file, eg: "testdata/home.json"
+func LoadFixture(t testing.TB, filePath string) interface{} {
+	t.Helper()
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("could not read fixture: %s", err)
+		return nil
+	}
+	var data interface{}
+	switch filepath.Ext(filePath) {
+	case ".json":
+		err = json.Unmarshal(b, &data)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &data)
+	default:
+		err = fmt.Errorf("unknown extension, should be one of: .json, .yaml, .yml")
+	}
+	if err != nil {
+		t.Fatalf("could not parse fixture %q: %s", filePath, err)
+		return nil
+	}
+	return data
+}
+
+// Render renders the template with data, the test fails if there is any error.
+func Render(t testing.TB, tm *templatemanager.TemplateManager, name string, data interface{}) string {
+	t.Helper()
+	got, _ := render(t, tm, name, data)
+	return got
+}
+
+func render(t testing.TB, tm *templatemanager.TemplateManager, name string, data interface{}) (string, bool) {
+	t.Helper()
+	out := &strings.Builder{}
+	if err := tm.ExecuteTemplate(out, name, data); err != nil {
+		t.Fatalf("could not render template %q: %s", name, err)
+		return "", false
+	}
+	return out.String(), true
+}
+
+// AssertGolden compares got with the golden file(relative to GoldenDir), both are normalized by options.
+// The golden file is written(not normalized) instead if the flag -templatemanagertest.update is set.
+func AssertGolden(t testing.TB, got string, goldenName string, options ...Options) {
+	t.Helper()
+	o := getOptions(options)
+	goldenPath := filepath.Join(o.GoldenDir, filepath.FromSlash(goldenName))
+	if isUpdating() {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			t.Fatalf("could not create dir of golden file %q: %s", goldenPath, err)
+			return
+		}
+		if err := ioutil.WriteFile(goldenPath, []byte(got), 0644); err != nil {
+			t.Fatalf("could not update golden file %q: %s", goldenPath, err)
+		}
+		return
+	}
+	b, err := ioutil.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("could not read golden file %q(run with -%s to create it): %s", goldenPath, UpdateFlagName, err)
+		return
+	}
+	want := Normalize(string(b), o)
+	if got = Normalize(got, o); got != want {
+		t.Errorf("output does not match golden file %q(run with -%s to update it):\n%s", goldenPath, UpdateFlagName, Diff(want, got))
+	}
+}
+
+// AssertRenderGolden renders the template with data and compares the output with the golden file, see: AssertGolden.
+func AssertRenderGolden(t testing.TB, tm *templatemanager.TemplateManager, name string, data interface{}, goldenName string, options ...Options) {
+	t.Helper()
+	got, ok := render(t, tm, name, data)
+	if !ok {
+		return
+	}
+	AssertGolden(t, got, goldenName, options...)
+}
@@ -0,0 +1,146 @@
+package templatemanagertest
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darkdarkfruit/templatemanager"
+)
+
+// fakeTB records failures instead of failing the test.
+type fakeTB struct {
+	testing.TB
+	failed   bool
+	messages []string
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...interface{}) {
+	f.failed = true
+	f.messages = append(f.messages, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Fatalf(format string, args ...interface{}) {
+	f.Errorf(format, args...)
+}
+
+var homeFiles = map[string]string{
+	"context/layout/layout.tpl.html": "<html>\n  {{ template \"content\" . }}\n</html>",
+	"main/home.tpl.html":             `{{ define "content" }}<h1>hi {{ .name }}</h1>{{ end }}`,
+}
+
+func TestNormalize(t *testing.T) {
+	s := " <ul>\r\n\n   <li>a</li>  \n\t<li>b</li>\n</ul>\n"
+	tests := []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{"none", Options{}, " <ul>\n\n   <li>a</li>  \n\t<li>b</li>\n</ul>\n"},
+		{"TrimSpace", Options{TrimSpace: true}, "<ul>\n\n   <li>a</li>  \n\t<li>b</li>\n</ul>"},
+		{"TrimLines", Options{TrimLines: true}, "<ul>\n\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"IgnoreBlankLines", Options{IgnoreBlankLines: true}, " <ul>\n   <li>a</li>  \n\t<li>b</li>\n</ul>"},
+		{"CollapseSpace", Options{CollapseSpace: true}, "<ul> <li>a</li> <li>b</li> </ul>"},
+	}
+	for _, tt := range tests {
+		if got := Normalize(s, tt.options); got != tt.want {
+			t.Errorf("%s: Normalize() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		want string
+		got  string
+		diff string
+	}{
+		{"a\nb", "a\nb", ""},
+		{"a\nb\nc", "a\nx\nc", "--- want\n+++ got\n@@ want line 1 @@\n  a\n- b\n+ x\n  c\n"},
+		{"1\n2\n3\n4\n5\n6\n7", "1\n2\n3\n4\n5\n6\n8", "--- want\n+++ got\n@@ want line 5 @@\n  5\n  6\n- 7\n+ 8\n"},
+		{"a", "a\nb", "--- want\n+++ got\n@@ want line 1 @@\n  a\n+ b\n"},
+	}
+	for _, tt := range tests {
+		if got := Diff(tt.want, tt.got); got != tt.diff {
+			t.Errorf("Diff(%q, %q) = %q, want %q", tt.want, tt.got, got, tt.diff)
+		}
+	}
+}
+
+func TestAssertRenderGolden(t *testing.T) {
+	data := map[string]string{"name": "alice"}
+	AssertRenderGolden(t, NewManager(t, homeFiles), "home", data, "home.html")
+
+	// whitespaces are normalized by options
+	files := map[string]string{
+		"context/layout/layout.tpl.html": "<html>\n\n{{ template \"content\" . }}   \n</html>\n",
+		"main/home.tpl.html":             homeFiles["main/home.tpl.html"],
+	}
+	AssertRenderGolden(t, NewManager(t, files), "home", data, "home.html", Options{TrimLines: true, IgnoreBlankLines: true})
+
+	// templates from files
+	AssertRenderGolden(t, NewManagerFromDir(t, WriteFiles(t, homeFiles)), "home", data, "home.html")
+
+	f := &fakeTB{}
+	AssertRenderGolden(f, NewManager(t, homeFiles), "home", map[string]string{"name": "bob"}, "home.html")
+	if !f.failed || len(f.messages) != 1 || !strings.Contains(f.messages[0], "-   <h1>hi alice</h1>\n+   <h1>hi bob</h1>") {
+		t.Errorf("AssertRenderGolden() of a different output, messages = %q", f.messages)
+	}
+
+	f = &fakeTB{}
+	AssertRenderGolden(f, NewManager(t, homeFiles), "home", data, "none.html")
+	if !f.failed || len(f.messages) != 1 || !strings.Contains(f.messages[0], "-"+UpdateFlagName) {
+		t.Errorf("AssertRenderGolden() without golden file, messages = %q", f.messages)
+	}
+
+	f = &fakeTB{}
+	AssertRenderGolden(f, NewManager(t, homeFiles), "none", data, "home.html")
+	if !f.failed || len(f.messages) != 1 || !strings.Contains(f.messages[0], `could not render template "none"`) {
+		t.Errorf("AssertRenderGolden() of a missing template, messages = %q", f.messages)
+	}
+}
+
+// a test package may define its own "-update" flag.
+var _ = flag.Bool("update", false, "update flag of the test package")
+
+func TestAssertGolden_Update(t *testing.T) {
+	if err := flag.Set(UpdateFlagName, "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer flag.Set(UpdateFlagName, "false")
+
+	dir := t.TempDir()
+	AssertGolden(t, "new output", "sub/new.html", Options{GoldenDir: dir})
+	b, err := ioutil.ReadFile(filepath.Join(dir, "sub", "new.html"))
+	if err != nil || string(b) != "new output" {
+		t.Errorf("golden file after updating = %q, err = %v", b, err)
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	root := WriteFiles(t, map[string]string{
+		"home.json": `{"name": "alice"}`,
+		"home.yaml": "name: alice\n",
+	})
+	tm := NewManager(t, homeFiles)
+	for _, name := range []string{"home.json", "home.yaml"} {
+		data := LoadFixture(t, filepath.Join(root, name))
+		if got := Render(t, tm, "home", data); got != "<html>\n  <h1>hi alice</h1>\n</html>" {
+			t.Errorf("Render() with fixture %q = %q", name, got)
+		}
+	}
+}
+
+func TestNewManager_Configure(t *testing.T) {
+	tm := NewManager(t, homeFiles, func(conf *templatemanager.TemplateConfig) {
+		conf.DirOfMainRelativeToRoot = "context"
+	})
+	if tm.Config.DirOfMainRelativeToRoot != "context" {
+		t.Errorf("DirOfMainRelativeToRoot = %q, want %q", tm.Config.DirOfMainRelativeToRoot, "context")
+	}
+}
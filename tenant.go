@@ -0,0 +1,199 @@
+package templatemanager
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"path"
+	"strings"
+)
+
+// Tenants.
+//
+// Every tenant gets a manager whose loader layers the tenant's templates on top of the shared ones.
+// Templates of a tenant are cached by tenant + standard name. eg: "C->main/demo/demo1.tpl.html?tenant=acme"
+// Tenant managers copy the loader, modes, sandboxes, string templates and FuncMap of the manager, so they are removed
+// (and created again on the next request) when any of them changes.
+//
+//	tm.ExecuteTemplate(w, "demo/demo1?tenant=acme", data)
+//	tm.ExecuteTemplateContext(templatemanager.WithTenant(ctx, "acme"), w, "demo/demo1", data)
+
+const OptionTenantKey = "tenant"
+
+type tenantContextKey struct{}
+
+// WithTenant returns a context carrying the tenant, see: ExecuteTemplateContext.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant of the context, "" if not set.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// SetTenantLoaderFunc sets the loader of the tenant layer. Default is a FileLoader of DirOfTenants/tenant.
+func (tm *TemplateManager) SetTenantLoaderFunc(f func(tenant string) Loader) {
+	tm.tenantMutex.Lock()
+	defer tm.tenantMutex.Unlock()
+	tm.tenantLoader = f
+	tm.tenants = make(map[string]*TemplateManager)
+	tm.tenantOrder = nil
+}
+
+func (tm *TemplateManager) getTenantOfTemplateName(templateName string) string {
+	if !strings.Contains(templateName, OptionsSeparator) {
+		return ""
+	}
+	te := newTemplateEnvByParsing(templateName, tm.getModePrefixes()...)
+	return te.Options[OptionTenantKey]
+}
+
+// GetTenant returns the tenant of the manager, "" if it is not a tenant manager.
+func (tm *TemplateManager) GetTenant() string {
+	return tm.tenant
+}
+
+// GetTenants returns the tenants kept in memory, least recently used first.
+func (tm *TemplateManager) GetTenants() []string {
+	tm.tenantMutex.Lock()
+	defer tm.tenantMutex.Unlock()
+	return append([]string(nil), tm.tenantOrder...)
+}
+
+// checkTenant checks that the tenant is a valid dir name.
+func checkTenant(tenant string) error {
+	if tenant == "" || tenant != path.Base(tenant) || tenant == "." || tenant == ".." {
+		return fmt.Errorf("invalid tenant: %q", tenant)
+	}
+	return nil
+}
+
+// GetTenantManager returns the manager of the tenant, it is created(without parsing templates) if not exists.
+// The least recently used tenant is removed if there are more than MaxTenants tenants.
+func (tm *TemplateManager) GetTenantManager(tenant string) (*TemplateManager, error) {
+	if tm.tenant != "" {
+		return nil, fmt.Errorf("could not get tenant %q of tenant manager %q", tenant, tm.tenant)
+	}
+	if err := checkTenant(tenant); err != nil {
+		return nil, err
+	}
+
+	tm.tenantMutex.Lock()
+	defer tm.tenantMutex.Unlock()
+	if child, ok := tm.tenants[tenant]; ok {
+		tm.touchTenant(tenant)
+		return child, nil
+	}
+
+	var tenantLoader Loader
+	if tm.tenantLoader != nil {
+		tenantLoader = tm.tenantLoader(tenant)
+	} else if tm.Config.DirOfTenants != "" {
+		tenantLoader = NewFileLoader(path.Join(tm.Config.DirOfTenants, tenant))
+	} else {
+		return nil, fmt.Errorf("could not load templates of tenant %q: neither DirOfTenants nor tenant loader is set", tenant)
+	}
+
+	tm.templateMutex.RLock()
+	config := tm.Config
+	tm.templateMutex.RUnlock()
+	config.FuncMap = make(template.FuncMap)
+	for k, v := range tm.getFuncMap() {
+		config.FuncMap[k] = v
+	}
+	child := New(config)
+	child.tenant = tenant
+	child.modes = tm.GetModes()
+	child.loader = NewLayeredLoader(tenantLoader, tm.GetLoader())
+	tm.sandboxMutex.RLock()
+	if tm.tenantSandbox != nil {
+		sandbox := *tm.tenantSandbox
+		sandbox.Loader = tenantLoader
+		child.sandbox = &sandbox
+	} else {
+		child.sandbox = tm.sandbox
+	}
+	tm.sandboxMutex.RUnlock()
+	tm.stringMutex.RLock()
+	for k, v := range tm.stringTemplates {
+		child.stringTemplates[k] = v
+	}
+	tm.stringMutex.RUnlock()
+	child.prepare()
+
+	tm.tenants[tenant] = child
+	tm.touchTenant(tenant)
+	for tm.Config.MaxTenants > 0 && len(tm.tenantOrder) > tm.Config.MaxTenants {
+		evicted := tm.tenantOrder[0]
+		tm.tenantOrder = tm.tenantOrder[1:]
+		delete(tm.tenants, evicted)
+		if tm.DoShowDebugMessage() {
+			log.Printf("Removed templates of the least recently used tenant: %q", evicted)
+		}
+	}
+	return child, nil
+}
+
+// touchTenant moves the tenant to the end of tenantOrder. tenantMutex must be held.
+func (tm *TemplateManager) touchTenant(tenant string) {
+	for i, t := range tm.tenantOrder {
+		if t == tenant {
+			tm.tenantOrder = append(tm.tenantOrder[:i], tm.tenantOrder[i+1:]...)
+			break
+		}
+	}
+	tm.tenantOrder = append(tm.tenantOrder, tenant)
+}
+
+// RemoveTenant removes templates of the tenant from memory, they will be parsed again on the next request.
+func (tm *TemplateManager) RemoveTenant(tenant string) {
+	tm.tenantMutex.Lock()
+	defer tm.tenantMutex.Unlock()
+	delete(tm.tenants, tenant)
+	for i, t := range tm.tenantOrder {
+		if t == tenant {
+			tm.tenantOrder = append(tm.tenantOrder[:i], tm.tenantOrder[i+1:]...)
+			break
+		}
+	}
+}
+
+// removeTenants removes all tenant managers, they are created again from the changed manager on the next request.
+func (tm *TemplateManager) removeTenants() {
+	tm.tenantMutex.Lock()
+	defer tm.tenantMutex.Unlock()
+	if len(tm.tenants) > 0 && tm.DoShowDebugMessage() {
+		log.Printf("Removed templates of tenants: %q", tm.tenantOrder)
+	}
+	tm.tenants = make(map[string]*TemplateManager)
+	tm.tenantOrder = nil
+}
+
+// ReloadTenant re-parses the changed templates of the tenant, see: ReloadChanged.
+func (tm *TemplateManager) ReloadTenant(tenant string) ([]string, error) {
+	tm.tenantMutex.Lock()
+	child, ok := tm.tenants[tenant]
+	tm.tenantMutex.Unlock()
+	if !ok {
+		return nil, nil
+	}
+	return child.ReloadChanged()
+}
+
+// ExecuteTemplateContext is the same as ExecuteTemplate, but renders the templates of the tenant of ctx(if set).
+func (tm *TemplateManager) ExecuteTemplateContext(ctx context.Context, out io.Writer, templateName string, data interface{}) error {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" || tm.tenant != "" {
+		return tm.ExecuteTemplate(out, templateName, data)
+	}
+	child, err := tm.GetTenantManager(tenant)
+	if err != nil {
+		log.Printf("TemplateManager get tenant error: %s", err)
+		return err
+	}
+	return child.ExecuteTemplate(out, templateName, data)
+}
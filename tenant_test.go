@@ -0,0 +1,144 @@
+package templatemanager
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateManager_Tenants(t *testing.T) {
+	dir := t.TempDir()
+	base, tenants := filepath.Join(dir, "templates"), filepath.Join(dir, "tenants")
+	writeTemplateFiles(t, base, map[string]string{
+		"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`,
+		"context/partial/logo.tpl.html":  `{{ define "logo" }}logo{{ end }}`,
+		"main/home.tpl.html":             `{{ define "content" }}home {{ template "logo" . }}{{ end }}`,
+	})
+	writeTemplateFiles(t, tenants, map[string]string{
+		"acme/context/partial/logo.tpl.html": `{{ define "logo" }}acme logo{{ end }}`,
+		"beta/main/home.tpl.html":            `{{ define "content" }}beta home {{ template "logo" . }}{{ end }}`,
+	})
+
+	conf := NewDefaultConfig(false)
+	conf.DirOfRoot = base
+	conf.DirOfTenants = tenants
+	conf.MaxTenants = 2
+	tm := New(conf)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		templateName string
+		want         string
+		wantErr      bool
+	}{
+		{"shared", context.Background(), "home", "<html>home logo</html>", false},
+		{"tenant by option", context.Background(), "home?tenant=acme", "<html>home acme logo</html>", false},
+		{"tenant by context", WithTenant(context.Background(), "beta"), "home", "<html>beta home logo</html>", false},
+		{"tenant without overrides", WithTenant(context.Background(), "gamma"), "home", "<html>home logo</html>", false},
+		{"invalid tenant", context.Background(), "home?tenant=../acme", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := tm.ExecuteTemplateContext(tt.ctx, out, tt.templateName, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteTemplateContext() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("ExecuteTemplateContext() got = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	// acme is the least recently used one, so it has been removed.
+	if tenants := tm.GetTenants(); len(tenants) != 2 || tenants[0] != "beta" || tenants[1] != "gamma" {
+		t.Errorf("GetTenants() got = %q", tenants)
+	}
+	beta, err := tm.GetTenantManager("beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := beta.GetTemplate("C->main/home.tpl.html?tenant=beta"); !ok {
+		t.Errorf("GetTemplate() could not find the template by tenant + standard name, got: %q", beta.GetTemplateNames())
+	}
+
+	writeTemplateFiles(t, tenants, map[string]string{
+		"beta/context/partial/logo.tpl.html": `{{ define "logo" }}beta logo{{ end }}`,
+	})
+	if _, err := tm.ReloadTenant("beta"); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := tm.ExecuteTemplate(out, "home?tenant=beta", nil); err != nil || out.String() != "<html>beta home beta logo</html>" {
+		t.Errorf("ExecuteTemplate() got = %q, err = %v", out.String(), err)
+	}
+}
+
+func TestTemplateManager_TenantsAfterParentChanges(t *testing.T) {
+	dir := t.TempDir()
+	base, tenants := filepath.Join(dir, "templates"), filepath.Join(dir, "tenants")
+	writeTemplateFiles(t, base, map[string]string{
+		"context/layout/layout.tpl.html": `<html>{{ template "content" . }}</html>`,
+		"main/home.tpl.html":             `{{ define "content" }}home{{ end }}`,
+	})
+	writeTemplateFiles(t, tenants, map[string]string{
+		"acme/main/card.tpl.html": `acme card`,
+	})
+	conf := NewDefaultConfig(false)
+	conf.DirOfRoot = base
+	conf.DirOfTenants = tenants
+	tm := New(conf)
+	if err := tm.Init(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.AddTemplateString("S->main/banner", `{{ define "content" }}old banner{{ end }}`); err != nil {
+		t.Fatal(err)
+	}
+
+	execute := func(templateName string) string {
+		out := &bytes.Buffer{}
+		if err := tm.ExecuteTemplate(out, templateName, nil); err != nil {
+			t.Errorf("ExecuteTemplate(%q) error = %v", templateName, err)
+		}
+		return out.String()
+	}
+	if got := execute("S->main/banner?tenant=acme"); got != "<html>old banner</html>" {
+		t.Errorf("ExecuteTemplate() got = %q", got)
+	}
+
+	// string templates, modes and funcs registered after the tenant exists reach the tenant.
+	if err := tm.AddTemplateString("S->main/banner", `{{ define "content" }}new banner{{ end }}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.AddTemplateString("S->main/promo", `{{ define "content" }}promo{{ end }}`); err != nil {
+		t.Fatal(err)
+	}
+	if got := execute("S->main/banner?tenant=acme"); got != "<html>new banner</html>" {
+		t.Errorf("ExecuteTemplate() of a replaced string template got = %q", got)
+	}
+	if got := execute("S->main/promo?tenant=acme"); got != "<html>promo</html>" {
+		t.Errorf("ExecuteTemplate() of a new string template got = %q", got)
+	}
+	if err := tm.RegisterMode(componentMode{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := execute("P->main/card.tpl.html?tenant=acme"); got != "acme card" {
+		t.Errorf("ExecuteTemplate() of a new mode got = %q", got)
+	}
+	if err := tm.AddFuncs(template.FuncMap{"shout": strings.ToUpper}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.AddTemplateString("S->main/shout", `{{ define "content" }}{{ shout "hi" }}{{ end }}`); err != nil {
+		t.Fatal(err)
+	}
+	if got := execute("S->main/shout?tenant=acme"); got != "<html>HI</html>" {
+		t.Errorf("ExecuteTemplate() with a new func got = %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package templatemanager
 
 import (
 	"path"
+	"sort"
 	"strings"
 )
 
@@ -10,19 +11,58 @@ type TemplateModePrefix string
 const TemplateModeContextPrefix TemplateModePrefix = "C->"
 const TemplateModeFilesPrefix TemplateModePrefix = "F->"
 const FilesSeparator = ";"
+const EntrySeparator = "@"
+const OptionsSeparator = "?"
+const OptionSeparator = "&"
+const OptionLayoutKey = "layout"
 
+// TemplateEnv is the parsed templateName. Grammar:
+//
+//	[mode-prefix] files [ "@" entry ] [ "?" key=value { "&" key=value } ]
+//
+//	eg: "F->main/a.html;main/b.html@b.html"  (FilesMode, execute template "b.html")
+//	 or "C->main/demo/demo1.tpl.html@content" (ContextMode, execute the defined template "content" only)
+//	 or "main/demo/demo1.tpl.html?layout=context/layout/simple.tpl.html" (ContextMode with another layout)
 type TemplateEnv struct {
-	Mode  TemplateModePrefix // template env: "C->" or "F->"
-	Names []string           // template names. ContextEnv has one "Names" only.
+	Mode    TemplateModePrefix // template env: "C->" or "F->"
+	Names   []string           // template names. ContextEnv has one "Names" only.
+	Entry   string             // template to execute. default: base name of layout(ContextMode) or of the first file(FilesMode)
+	Layout  string             // layout file path relative to root(ContextMode). default: FilePathOfLayoutRelativeToRoot
+	Options map[string]string  // other options, only "tenant" is accepted by NewTemplateEnv
 }
 
 func (self TemplateEnv) String() string {
 	return self.StandardTemplateName()
 }
 
+// StandardTemplateName returns the canonical name, options are sorted by key so the name is stable as a cache key.
 func (self *TemplateEnv) StandardTemplateName() string {
 	s := string(self.Mode)
-	return s + strings.Join(self.Names, FilesSeparator)
+	s += strings.Join(self.Names, FilesSeparator)
+	if self.Entry != "" {
+		s += EntrySeparator + self.Entry
+	}
+
+	options := make(map[string]string, len(self.Options)+1)
+	for k, v := range self.Options {
+		options[k] = v
+	}
+	if self.Layout != "" {
+		options[OptionLayoutKey] = self.Layout
+	}
+	if len(options) == 0 {
+		return s
+	}
+	var keys []string
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+options[k])
+	}
+	return s + OptionsSeparator + strings.Join(pairs, OptionSeparator)
 }
 
 func (self *TemplateEnv) ToContextMode() *TemplateEnv {
@@ -45,26 +85,60 @@ func getFilesFromTemplateName(tplName, prefix, separator string) []string {
 	}
 	return standarizedNamesSlice
 }
-func NewTemplateEnvByParsing(tplName string) *TemplateEnv {
-	ctxPrefix := string(TemplateModeContextPrefix)
-	filesPrefix := string(TemplateModeFilesPrefix)
-	if strings.HasPrefix(tplName, ctxPrefix) {
-		return &TemplateEnv{
-			Mode:  TemplateModeContextPrefix,
-			Names: getFilesFromTemplateName(tplName, ctxPrefix, FilesSeparator),
-		}
 
-	} else if strings.HasPrefix(tplName, filesPrefix) {
-		return &TemplateEnv{
-			Mode:  TemplateModeFilesPrefix,
-			Names: getFilesFromTemplateName(tplName, filesPrefix, FilesSeparator),
+// splitTemplateName splits tplName into files part, entry and options.
+func splitTemplateName(tplName string) (files string, entry string, options map[string]string) {
+	files = tplName
+	if i := strings.Index(files, OptionsSeparator); i >= 0 {
+		for _, pair := range strings.Split(files[i+len(OptionsSeparator):], OptionSeparator) {
+			kv := strings.SplitN(pair, "=", 2)
+			k := strings.Trim(kv[0], " ")
+			if k == "" {
+				continue
+			}
+			if options == nil {
+				options = make(map[string]string)
+			}
+			if len(kv) == 2 {
+				options[k] = strings.Trim(kv[1], " ")
+			} else {
+				options[k] = ""
+			}
 		}
-	} else {
-		return &TemplateEnv{
-			Mode:  TemplateModeContextPrefix,
-			Names: getFilesFromTemplateName(tplName, ctxPrefix, FilesSeparator),
+		files = files[:i]
+	}
+	if i := strings.LastIndex(files, EntrySeparator); i >= 0 {
+		entry = strings.Trim(files[i+len(EntrySeparator):], " ")
+		files = files[:i]
+	}
+	return
+}
+
+func NewTemplateEnvByParsing(tplName string) *TemplateEnv {
+	return newTemplateEnvByParsing(tplName, TemplateModeContextPrefix, TemplateModeFilesPrefix)
+}
+
+// newTemplateEnvByParsing parses tplName with the mode prefixes. Default mode is ContextMode.
+func newTemplateEnvByParsing(tplName string, prefixes ...TemplateModePrefix) *TemplateEnv {
+	files, entry, options := splitTemplateName(strings.Trim(tplName, " "))
+
+	te := &TemplateEnv{Mode: TemplateModeContextPrefix, Entry: entry}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(files, string(prefix)) {
+			te.Mode = prefix
+			break
 		}
 	}
+	te.Names = getFilesFromTemplateName(files, string(te.Mode), FilesSeparator)
+
+	if layout, ok := options[OptionLayoutKey]; ok {
+		te.Layout = layout
+		delete(options, OptionLayoutKey)
+	}
+	if len(options) > 0 {
+		te.Options = options
+	}
+	return te
 }
 
 func (self *TemplateEnv) IsFilesMode() bool {
@@ -72,7 +146,7 @@ func (self *TemplateEnv) IsFilesMode() bool {
 }
 
 func (self *TemplateEnv) IsContextMode() bool {
-	return self.Mode == TemplateModeContextPrefix || !self.IsFilesMode()
+	return self.Mode == TemplateModeContextPrefix || self.Mode == ""
 }
 
 func (self *TemplateEnv) GetFilePaths(dir string) []string {
@@ -83,3 +157,15 @@ func (self *TemplateEnv) GetFilePaths(dir string) []string {
 	}
 	return paths
 }
+
+// GetEntryName returns the name of the template to execute. defaultName is used if no entry is specified.
+// An entry which is one of the files is converted to the file's base name(which is the template name of the file).
+func (self *TemplateEnv) GetEntryName(defaultName string) string {
+	if self.Entry == "" {
+		return defaultName
+	}
+	if ContainsString(self.Names, self.Entry) {
+		return path.Base(self.Entry)
+	}
+	return self.Entry
+}
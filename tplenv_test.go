@@ -0,0 +1,54 @@
+package templatemanager
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewTemplateEnvByParsing(t *testing.T) {
+	tests := []struct {
+		name         string
+		tplName      string
+		want         TemplateEnv
+		wantStandard string
+	}{
+		{
+			name:         "default ContextMode",
+			tplName:      "main/demo/demo1.tpl.html",
+			want:         TemplateEnv{Mode: TemplateModeContextPrefix, Names: []string{"main/demo/demo1.tpl.html"}},
+			wantStandard: "C->main/demo/demo1.tpl.html",
+		},
+		{
+			name:         "FilesMode with entry",
+			tplName:      "F-> a.html; b.html @ b.html",
+			want:         TemplateEnv{Mode: TemplateModeFilesPrefix, Names: []string{"a.html", "b.html"}, Entry: "b.html"},
+			wantStandard: "F->a.html;b.html@b.html",
+		},
+		{
+			name:    "ContextMode with entry, layout and options",
+			tplName: "C->main/demo/demo1.tpl.html@content?z=1&layout=context/layout/simple.tpl.html&a",
+			want: TemplateEnv{
+				Mode:    TemplateModeContextPrefix,
+				Names:   []string{"main/demo/demo1.tpl.html"},
+				Entry:   "content",
+				Layout:  "context/layout/simple.tpl.html",
+				Options: map[string]string{"z": "1", "a": ""},
+			},
+			wantStandard: "C->main/demo/demo1.tpl.html@content?a=&layout=context/layout/simple.tpl.html&z=1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTemplateEnvByParsing(tt.tplName)
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("NewTemplateEnvByParsing() got = %#v, want %#v", *got, tt.want)
+			}
+			if s := got.StandardTemplateName(); s != tt.wantStandard {
+				t.Errorf("StandardTemplateName() got = %q, want %q", s, tt.wantStandard)
+			}
+			if s := NewTemplateEnvByParsing(tt.wantStandard).StandardTemplateName(); s != tt.wantStandard {
+				t.Errorf("StandardTemplateName() is not stable, got = %q, want %q", s, tt.wantStandard)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package templatemanager
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// ConfigError holds all problems of a TemplateConfig, see: Validate.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid template config(%d problems): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+// Validate checks the config(and the templates dirs by the loader), all problems are returned together as a *ConfigError.
+// It is called by Init.
+func (tm *TemplateManager) Validate() error {
+	var problems []string
+	addProblem := func(format string, a ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, a...))
+	}
+	conf := tm.Config
+
+	tm.versionMutex.RLock()
+	hasCustomLoader := tm.loader != nil
+	tm.versionMutex.RUnlock()
+	if conf.DirOfRoot == "" {
+		addProblem("DirOfRoot is empty")
+	} else if !hasCustomLoader {
+		// the default loader reads files from DirOfRoot(and DirsOfOverrideRoot).
+		for _, dir := range append([]string{conf.DirOfRoot}, conf.DirsOfOverrideRoot...) {
+			if info, err := os.Stat(dir); err != nil {
+				addProblem("root dir %q does not exist: %s", dir, err)
+			} else if !info.IsDir() {
+				addProblem("root dir %q is not a directory", dir)
+			}
+		}
+	}
+
+	loader := tm.GetLoader()
+	for _, d := range []struct{ key, dir string }{
+		{"DirOfMainRelativeToRoot", conf.DirOfMainRelativeToRoot},
+		{"DirOfContextRelativeToRoot", conf.DirOfContextRelativeToRoot},
+	} {
+		if !isRelativeToRoot(d.dir) {
+			addProblem("%s %q is not under DirOfRoot", d.key, d.dir)
+			continue
+		}
+		if _, err := loader.List(d.dir); err != nil {
+			addProblem("%s %q does not exist under DirOfRoot %q: %s", d.key, d.dir, conf.DirOfRoot, err)
+		}
+	}
+
+	layout := conf.FilePathOfLayoutRelativeToRoot
+	if !isRelativeToRoot(layout) || strings.Trim(path.Clean(layout), "/.") == "" {
+		addProblem("FilePathOfLayoutRelativeToRoot %q is not a file under DirOfRoot", layout)
+	} else if _, ok := tm.getStringTemplate(tm.GetFilePathOfBase()); !ok {
+		if _, err := loader.Stat(path.Clean(layout)); err != nil {
+			addProblem("layout file %q does not exist under DirOfRoot %q: %s", layout, conf.DirOfRoot, err)
+		}
+	}
+
+	extensions := tm.getExtensions()
+	if ContainsString(extensions, "") {
+		addProblem("Extension is empty")
+	}
+	for _, patterns := range [][]string{conf.IncludePatterns, conf.ExcludePatterns} {
+		for _, pattern := range patterns {
+			for _, p := range strings.Split(pattern, "/") {
+				if _, err := path.Match(p, ""); err != nil {
+					addProblem("invalid glob pattern %q: %s", pattern, err)
+					break
+				}
+			}
+		}
+	}
+
+	if conf.FuncMap == nil {
+		addProblem("FuncMap is nil")
+	}
+	// empty delims are the default "{{" and "}}"
+	if left, right := conf.Delims.get(); left == right {
+		addProblem("Delims %q and %q must be different", left, right)
+	}
+	if !ContainsString([]string{"", "default", "invalid", "zero", "error"}, conf.MissingKey) {
+		addProblem("MissingKey %q must be one of: \"default\", \"zero\", \"error\"", conf.MissingKey)
+	}
+	if !ContainsString([]string{"", DataCheckLog, DataCheckError, DataCheckStrict}, conf.DataCheck) {
+		addProblem("DataCheck %q must be one of: %q, %q, %q", conf.DataCheck, DataCheckLog, DataCheckError, DataCheckStrict)
+	}
+	if conf.MaxTenants < 0 {
+		addProblem("MaxTenants %d must not be negative", conf.MaxTenants)
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+	return nil
+}
+
+// isRelativeToRoot checks if the path(relative to root) does not go out of root.
+func isRelativeToRoot(p string) bool {
+	if path.IsAbs(p) {
+		return false
+	}
+	p = path.Clean(p)
+	return p != ".." && !strings.HasPrefix(p, "../")
+}
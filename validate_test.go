@@ -0,0 +1,95 @@
+package templatemanager
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateManager_Validate(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "templates")
+	writeTemplateFiles(t, root, map[string]string{
+		"context/layout/layout.tpl.html": `{{ template "content" . }}`,
+		"main/home.tpl.html":             `{{ define "content" }}home{{ end }}`,
+	})
+
+	tests := []struct {
+		name         string
+		modify       func(conf *TemplateConfig)
+		wantProblems []string
+	}{
+		{"valid", func(conf *TemplateConfig) {}, nil},
+		{"missing root", func(conf *TemplateConfig) {
+			conf.DirOfRoot = filepath.Join(root, "none")
+		}, []string{"root dir", "DirOfMainRelativeToRoot", "DirOfContextRelativeToRoot", "layout file"}},
+		{"missing dirs and layout", func(conf *TemplateConfig) {
+			conf.DirOfMainRelativeToRoot = "pages"
+			conf.DirOfContextRelativeToRoot = "../context"
+			conf.FilePathOfLayoutRelativeToRoot = "context/base.tpl.html"
+		}, []string{`DirOfMainRelativeToRoot "pages" does not exist`, `DirOfContextRelativeToRoot "../context" is not under DirOfRoot`, `layout file "context/base.tpl.html" does not exist`}},
+		{"layout out of root", func(conf *TemplateConfig) {
+			conf.FilePathOfLayoutRelativeToRoot = "/etc/passwd"
+		}, []string{`FilePathOfLayoutRelativeToRoot "/etc/passwd" is not a file under DirOfRoot`}},
+		{"extension, funcMap and delims", func(conf *TemplateConfig) {
+			conf.Extension = ""
+			conf.FuncMap = nil
+			conf.Delims = Delims{Left: "[[", Right: "[["}
+			conf.ExcludePatterns = []string{"main/[a"}
+		}, []string{"Extension is empty", "FuncMap is nil", `Delims "[[" and "[[" must be different`, `invalid glob pattern "main/[a"`}},
+		{"empty delims are the default", func(conf *TemplateConfig) {
+			conf.Delims = Delims{}
+			conf.MaxTenants = -1
+		}, []string{"MaxTenants -1 must not be negative"}},
+		{"delims same as the default", func(conf *TemplateConfig) {
+			conf.Delims = Delims{Left: "}}"}
+		}, []string{`Delims "}}" and "}}" must be different`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := NewDefaultConfig(false)
+			conf.DirOfRoot = root
+			tt.modify(&conf)
+			tm := New(conf)
+			err := tm.Validate()
+			if tt.wantProblems == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			var configErr *ConfigError
+			if !errors.As(err, &configErr) {
+				t.Fatalf("Validate() error = %v, want a *ConfigError", err)
+			}
+			if len(configErr.Problems) != len(tt.wantProblems) {
+				t.Errorf("Validate() problems = %q, want %d problems", configErr.Problems, len(tt.wantProblems))
+			}
+			for _, want := range tt.wantProblems {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want %q", err, want)
+				}
+			}
+			if initErr := tm.Init(true); initErr == nil || initErr.Error() != err.Error() {
+				t.Errorf("Init() error = %v, want %v", initErr, err)
+			}
+		})
+	}
+}
+
+func TestTemplateManager_Delims(t *testing.T) {
+	tm := newTestManager(t, false, map[string]string{
+		"context/layout/layout.tpl.html": `<title>[[ .Title ]]</title>[[ template "content" . ]]`,
+		"main/home.tpl.html":             `[[ define "content" ]]{{ .Title }}[[ end ]]`,
+	}, func(conf *TemplateConfig) {
+		conf.Delims = Delims{Left: "[[", Right: "]]"}
+	})
+	out := &bytes.Buffer{}
+	if err := tm.ExecuteTemplate(out, "home", map[string]string{"Title": "home"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<title>home</title>{{ .Title }}"; out.String() != want {
+		t.Errorf("ExecuteTemplate() = %q, want %q", out.String(), want)
+	}
+}